@@ -0,0 +1,99 @@
+package goat
+
+import (
+	"fmt"
+	"reflect"
+)
+
+type ProvideOption interface {
+	applyProvide(opts *provideOptions)
+}
+
+type provideOptions struct {
+	name       string
+	paramTags  []reflect.StructTag
+	as         []reflect.Type
+	group      string
+	order      int
+	private    bool
+	override   bool
+	conditions []condition
+	err        error
+}
+
+type provideOptionFunc func(opts *provideOptions)
+
+func (f provideOptionFunc) applyProvide(opts *provideOptions) {
+	f(opts)
+}
+
+// Name registers every component returned by the constructors of the same Provide call under the given name.
+func Name(name string) ProvideOption {
+	return provideOptionFunc(func(opts *provideOptions) {
+		opts.name = name
+	})
+}
+
+// ParamTags qualifies the constructor parameters in order with struct tags such as `name:"primary"`.
+func ParamTags(tags ...string) ProvideOption {
+	return provideOptionFunc(func(opts *provideOptions) {
+		for _, tag := range tags {
+			opts.paramTags = append(opts.paramTags, reflect.StructTag(tag))
+		}
+	})
+}
+
+// As additionally exposes the components of the same Provide call as the interfaces pointed to by ifaces,
+// for example goat.As(new(FooService)).
+func As(ifaces ...interface{}) ProvideOption {
+	return provideOptionFunc(func(opts *provideOptions) {
+		for _, iface := range ifaces {
+			t := reflect.TypeOf(iface)
+			if t == nil || t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Interface {
+				opts.fail(fmt.Errorf("goat.As expects a pointer to an interface, got %v", t))
+				continue
+			}
+			opts.as = append(opts.as, t.Elem())
+		}
+	})
+}
+
+// Group contributes the components of the same Provide call to the named value group.
+// Consumers receive every member as a slice through a `group:"..."` parameter tag.
+func Group(group string) ProvideOption {
+	return provideOptionFunc(func(opts *provideOptions) {
+		opts.group = group
+	})
+}
+
+// Order sets the position of group members, lower values come first.
+// Members with the same order keep their registration order.
+func Order(order int) ProvideOption {
+	return provideOptionFunc(func(opts *provideOptions) {
+		opts.order = order
+	})
+}
+
+// Private hides the components of the same Provide call from everything outside the enclosing Module.
+var Private ProvideOption = provideOptionFunc(func(opts *provideOptions) {
+	opts.private = true
+})
+
+func (opts *provideOptions) fail(err error) {
+	if opts.err == nil {
+		opts.err = err
+	}
+}
+
+func splitProvideOptions(args []interface{}) ([]interface{}, provideOptions) {
+	opts := provideOptions{}
+	targets := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		if opt, ok := arg.(ProvideOption); ok {
+			opt.applyProvide(&opts)
+			continue
+		}
+		targets = append(targets, arg)
+	}
+	return targets, opts
+}
@@ -0,0 +1,189 @@
+package goat
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type testDB struct {
+	dsn string
+}
+
+func Test_Goat_Name(t *testing.T) {
+	t.Run("should provide and inject named components of the same type", func(t *testing.T) {
+		var primary, replica *testDB
+		g := New(
+			Provide(func() *testDB { return &testDB{dsn: "primary"} }, Name("primary")),
+			Provide(func() *testDB { return &testDB{dsn: "replica"} }, Name("replica")),
+			Provide(func(p *testDB, r *testDB) *testFoo {
+				primary, replica = p, r
+				return newTestFoo()
+			}, ParamTags(`name:"primary"`, `name:"replica"`)),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, "primary", primary.dsn)
+		assert.Equal(t, "replica", replica.dsn)
+	})
+
+	t.Run("should not satisfy unnamed parameter with named component", func(t *testing.T) {
+		g := New(
+			Provide(func() *testDB { return &testDB{} }, Name("primary")),
+			Provide(func(db *testDB) *testFoo { return newTestFoo() }),
+		)
+		err := g.Start(context.Background())
+		assert.ErrorContains(t, err, "missing dependency *goat.testDB")
+	})
+
+	t.Run("should name the missing qualifier", func(t *testing.T) {
+		g := New(
+			Provide(func(db *testDB) *testFoo { return newTestFoo() }, ParamTags(`name:"primary"`)),
+		)
+		err := g.Start(context.Background())
+		assert.ErrorContains(t, err, `*goat.testDB[name="primary"]`)
+	})
+
+	t.Run("should reject more param tags than parameters", func(t *testing.T) {
+		g := New(Provide(newTestFoo, ParamTags(`name:"a"`)))
+		assert.Error(t, g.Start(context.Background()))
+	})
+}
+
+type testFooService interface {
+	Name() string
+}
+
+func (f *testFoo) Name() string {
+	return f.name
+}
+
+func Test_Goat_As(t *testing.T) {
+	t.Run("should inject concrete component where interface is requested", func(t *testing.T) {
+		var service testFooService
+		var foo *testFoo
+		g := New(
+			Provide(newTestFoo, As(new(testFooService))),
+			Provide(func(s testFooService, f *testFoo) *testBar {
+				service, foo = s, f
+				return newTestBar(f)
+			}),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, "foo", service.Name())
+		assert.Same(t, foo, service)
+	})
+
+	t.Run("should fail start if concrete type does not implement interface", func(t *testing.T) {
+		g := New(Provide(func() *testBar { return &testBar{} }, As(new(testFooService))))
+		err := g.Start(context.Background())
+		assert.ErrorContains(t, err, "*goat.testBar does not implement goat.testFooService")
+	})
+
+	t.Run("should fail start if argument is not a pointer to interface", func(t *testing.T) {
+		g := New(Provide(newTestFoo, As(testFoo{})))
+		assert.ErrorContains(t, g.Start(context.Background()), "pointer to an interface")
+	})
+
+	t.Run("should fail start if two providers claim the same interface without qualifier", func(t *testing.T) {
+		g := New(
+			Provide(newTestFoo, As(new(testFooService))),
+			Provide(func() *testDB { return &testDB{} }),
+			Provide(func() testFooService { return &testFoo{} }),
+		)
+		err := g.Start(context.Background())
+		assert.ErrorContains(t, err, "goat.testFooService provided by")
+		assert.ErrorContains(t, err, "goat.Name")
+	})
+
+	t.Run("should allow qualified bindings of the same interface", func(t *testing.T) {
+		var a, b testFooService
+		g := New(
+			Provide(func() *testFoo { return &testFoo{name: "a"} }, As(new(testFooService)), Name("a")),
+			Provide(func() *testDB { return &testDB{} }),
+			Provide(func() testFooService { return &testFoo{name: "b"} }, Name("b")),
+			Provide(func(x, y testFooService) *testBar {
+				a, b = x, y
+				return &testBar{}
+			}, ParamTags(`name:"a"`, `name:"b"`)),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, "a", a.Name())
+		assert.Equal(t, "b", b.Name())
+	})
+}
+
+type testHandler interface {
+	Path() string
+}
+
+type testRoute string
+
+func (r testRoute) Path() string {
+	return string(r)
+}
+
+func Test_Goat_Group(t *testing.T) {
+	newRoute := func(path string) func() testRoute {
+		return func() testRoute { return testRoute(path) }
+	}
+
+	t.Run("should inject every member of the group in registration order", func(t *testing.T) {
+		var handlers []testHandler
+		g := New(
+			Provide(newRoute("/a"), As(new(testHandler)), Group("handlers")),
+			Provide(func(hs []testHandler) *testBar {
+				handlers = hs
+				return &testBar{}
+			}, ParamTags(`group:"handlers"`)),
+			Provide(newRoute("/b"), newRoute("/c"), As(new(testHandler)), Group("handlers")),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		paths := make([]string, 0)
+		for _, h := range handlers {
+			paths = append(paths, h.Path())
+		}
+		assert.Equal(t, []string{"/a", "/b", "/c"}, paths)
+	})
+
+	t.Run("should sort members by explicit order", func(t *testing.T) {
+		var routes []testRoute
+		g := New(
+			Provide(newRoute("/a"), Group("routes")),
+			Provide(newRoute("/b"), Group("routes"), Order(-1)),
+			Provide(newRoute("/c"), Group("routes"), Order(1)),
+			Provide(newRoute("/d"), Group("routes")),
+			Provide(func(rs []testRoute) *testFoo {
+				routes = rs
+				return newTestFoo()
+			}, ParamTags(`group:"routes"`)),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, []testRoute{"/b", "/a", "/d", "/c"}, routes)
+	})
+
+	t.Run("should inject empty slice if nobody contributed", func(t *testing.T) {
+		var routes []testRoute
+		g := New(
+			Provide(func(rs []testRoute) *testFoo {
+				routes = rs
+				return newTestFoo()
+			}, ParamTags(`group:"routes"`)),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.NotNil(t, routes)
+		assert.Empty(t, routes)
+	})
+
+	t.Run("should not expose group members individually", func(t *testing.T) {
+		g := New(
+			Provide(newRoute("/a"), Group("routes")),
+			Provide(func(r testRoute) *testFoo { return newTestFoo() }),
+		)
+		assert.ErrorContains(t, g.Start(context.Background()), "missing dependency goat.testRoute")
+	})
+
+	t.Run("should reject group parameter that is not a slice", func(t *testing.T) {
+		g := New(Provide(func(r testRoute) *testFoo { return newTestFoo() }, ParamTags(`group:"routes"`)))
+		assert.ErrorContains(t, g.Start(context.Background()), "must be an unnamed slice")
+	})
+}
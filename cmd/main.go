@@ -16,7 +16,7 @@ func main() {
 func NewFoo() *Foo {
 	return &Foo{
 		OnStop: func(ctx context.Context) error {
-			fmt.Sprintf("Foo OnStop!")
+			fmt.Println("Foo OnStop!")
 			return nil
 		},
 	}
@@ -26,11 +26,11 @@ func NewBar(foo *Foo) *Bar {
 	return &Bar{
 		foo: foo,
 		OnStart: func(ctx context.Context) error {
-			fmt.Sprintf("Bar OnStart!")
+			fmt.Println("Bar OnStart!")
 			return nil
 		},
 		OnStop: func(ctx context.Context) error {
-			fmt.Sprintf("Bar OnStop!")
+			fmt.Println("Bar OnStop!")
 			return nil
 		},
 	}
@@ -46,7 +46,7 @@ type Bar struct {
 }
 
 type FooConfiguration struct {
-	AppName string `properties:"app.ame"`
+	AppName string `properties:"app.name"`
 	Version string `properties:"app.version"`
 }
 
@@ -0,0 +1,165 @@
+package goat
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// ConditionOutcome records a condition evaluated while the graph was built and why it matched or not.
+type ConditionOutcome struct {
+	Module    string
+	Target    string
+	Condition string
+	Matched   bool
+	Message   string
+}
+
+func (o ConditionOutcome) String() string {
+	decision := "excluded"
+	if o.Matched {
+		decision = "included"
+	}
+	return fmt.Sprintf("%s > %s: %s %s, %s", o.Module, o.Target, o.Condition, decision, o.Message)
+}
+
+type condition struct {
+	name     string
+	evaluate func(g *Goat, s *scope, p *provider) (bool, string)
+	// missing conditions look at what is provided, so they are evaluated after every other registration.
+	missing bool
+}
+
+// ConditionalOnProperty registers the constructors of the same Provide call only if the property key has value.
+// An empty value matches any value other than "false".
+func ConditionalOnProperty(key string, value string) ProvideOption {
+	return provideOptionFunc(func(opts *provideOptions) {
+		opts.conditions = append(opts.conditions, condition{
+			name: fmt.Sprintf("ConditionalOnProperty(%q, %q)", key, value),
+			evaluate: func(g *Goat, s *scope, p *provider) (bool, string) {
+				actual, err := g.environment.GetRequiredProperty(key)
+				if err != nil {
+					return false, fmt.Sprintf("property %s is not set", key)
+				}
+				if value == "" {
+					return actual != "false", fmt.Sprintf("property %s=%s", key, actual)
+				}
+				if actual != value {
+					return false, fmt.Sprintf("property %s=%s, expected %s", key, actual, value)
+				}
+				return true, fmt.Sprintf("property %s=%s", key, actual)
+			},
+		})
+	})
+}
+
+// ConditionalOnMissing registers the constructors of the same Provide call only if nothing else visible
+// provides their result types. It is evaluated on Start after every other registration, including the conditional ones.
+func ConditionalOnMissing() ProvideOption {
+	return provideOptionFunc(func(opts *provideOptions) {
+		opts.conditions = append(opts.conditions, condition{
+			name:    "ConditionalOnMissing()",
+			missing: true,
+			evaluate: func(g *Goat, s *scope, p *provider) (bool, string) {
+				keys := make([]string, 0, len(p.results))
+				for _, r := range p.results {
+					if r.key.group != "" {
+						continue
+					}
+					target := s
+					if !p.private {
+						target = g.container.root
+					}
+					if exist := g.container.providersOf(target, r.key); len(exist) > 0 {
+						return false, fmt.Sprintf("%v is already provided by %s (%s)", r.key, exist[0].name, exist[0].location)
+					}
+					keys = append(keys, r.key.String())
+				}
+				return true, fmt.Sprintf("no provider of %s found", strings.Join(keys, ", "))
+			},
+		})
+	})
+}
+
+// OnProfile applies opts only if profile is active. A profile prefixed with "!" applies them only if it is not active.
+// Each decision is logged on New with the profile that caused it.
+func OnProfile(profile string, opts ...Option) Option {
+	_, file, line, _ := runtime.Caller(1)
+	location := fmt.Sprintf("%s:%d", file, line)
+
+	return optionFunc(func(m *module) {
+		name, negated := strings.CutPrefix(profile, "!")
+		active := m.goat.profile.Contains(name)
+		matched := active != negated
+
+		state := "not active"
+		if active {
+			state = "active"
+		}
+		m.goat.report(ConditionOutcome{
+			Module:    m.scope.path(),
+			Target:    fmt.Sprintf("%d option(s) (%s)", len(opts), location),
+			Condition: fmt.Sprintf("OnProfile(%q)", profile),
+			Matched:   matched,
+			Message:   fmt.Sprintf("profile %q is %s (active profiles: %s)", name, state, strings.Join(m.goat.profile.Get(), ",")),
+		})
+
+		if !matched {
+			return
+		}
+		for _, opt := range opts {
+			if opt == nil {
+				continue
+			}
+			opt.apply(m)
+		}
+	})
+}
+
+// Conditions returns every condition evaluated so far with its outcome, in evaluation order.
+func (g *Goat) Conditions() []ConditionOutcome {
+	return append([]ConditionOutcome(nil), g.conditions...)
+}
+
+func (g *Goat) evaluateConditions() error {
+	ordered := make([]pending, 0, len(g.container.pending))
+	missing := make([]pending, 0)
+	for _, pd := range g.container.pending {
+		if slices.ContainsFunc(pd.conditions, func(c condition) bool { return c.missing }) {
+			missing = append(missing, pd)
+		} else {
+			ordered = append(ordered, pd)
+		}
+	}
+	g.container.pending = g.container.pending[:0]
+	for _, pd := range append(ordered, missing...) {
+		matched := true
+		for _, cond := range pd.conditions {
+			ok, message := cond.evaluate(g, pd.scope, pd.provider)
+			g.report(ConditionOutcome{
+				Module:    pd.scope.path(),
+				Target:    fmt.Sprintf("%s (%s)", pd.provider.name, pd.provider.location),
+				Condition: cond.name,
+				Matched:   ok,
+				Message:   message,
+			})
+			if !ok {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		if err := g.container.register(pd.scope, pd.provider); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Goat) report(outcome ConditionOutcome) {
+	g.conditions = append(g.conditions, outcome)
+	g.logger.Printf("condition %v", outcome)
+}
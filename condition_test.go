@@ -0,0 +1,193 @@
+package goat
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"testing"
+	"time"
+)
+
+func Test_Goat_OnProfile(t *testing.T) {
+	newMailer := func(name string) func() *testFoo {
+		return func() *testFoo { return &testFoo{name: name} }
+	}
+
+	t.Run("should include options of active profile and exclude the others", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		var mailer *testFoo
+		g := New(
+			Profiles("local"),
+			Logger(log.New(buf, "", 0)),
+			OnProfile("local", Provide(newMailer("mock"))),
+			OnProfile("prod", Provide(newMailer("smtp"))),
+			Populate(&mailer),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, "mock", mailer.name)
+		assert.Contains(t, buf.String(), `OnProfile("local") included, profile "local" is active (active profiles: default,local)`)
+		assert.Contains(t, buf.String(), `OnProfile("prod") excluded, profile "prod" is not active`)
+		conditions := g.Conditions()
+		assert.Len(t, conditions, 2)
+		assert.Equal(t, "app", conditions[0].Module)
+		assert.True(t, conditions[0].Matched)
+		assert.False(t, conditions[1].Matched)
+	})
+
+	t.Run("should include options of negated profile if it is not active", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		var mailer *testFoo
+		g := New(
+			Profiles("local"),
+			Logger(log.New(buf, "", 0)),
+			OnProfile("!prod", Provide(newMailer("mock"))),
+			OnProfile("!local", Provide(newMailer("smtp"))),
+			Populate(&mailer),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, "mock", mailer.name)
+		assert.Contains(t, buf.String(), `OnProfile("!local")`)
+	})
+
+	t.Run("should evaluate profile conditions inside modules", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		g := New(
+			Logger(log.New(buf, "", 0)),
+			Profiles("prod"),
+			Module("mail",
+				OnProfile("prod", Provide(newMailer("smtp"))),
+			),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Contains(t, buf.String(), `condition app > mail > 1 option(s)`)
+	})
+
+	t.Run("should fail if app settings are passed to a module", func(t *testing.T) {
+		g := New(Module("mail", Profiles("prod")))
+		assert.ErrorContains(t, g.Start(context.Background()), "app > mail > app settings")
+	})
+
+	t.Run("should fail if app settings are passed to OnProfile", func(t *testing.T) {
+		g := New(Profiles("prod"), OnProfile("prod", StopTimeout(30*time.Second), HookConcurrency(-5)))
+		assert.ErrorContains(t, g.Start(context.Background()), "app > app settings such as goat.Profiles must be passed to goat.New directly")
+	})
+}
+
+func Test_Goat_Conditional(t *testing.T) {
+	quiet := Logger(log.New(&bytes.Buffer{}, "", 0))
+
+	t.Run("should register provider only if property has value", func(t *testing.T) {
+		var foo *testFoo
+		var db Optional[*testDB]
+		g := New(
+			quiet,
+			Properties(map[string]string{"cache.enabled": "true", "db.enabled": "false"}),
+			Provide(newTestFoo, ConditionalOnProperty("cache.enabled", "true")),
+			Provide(func() *testDB { return &testDB{} }, ConditionalOnProperty("db.enabled", "")),
+			Invoke(func(f *testFoo, d Optional[*testDB]) { foo, db = f, d }),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.NotNil(t, foo)
+		_, ok := db.Get()
+		assert.False(t, ok)
+
+		conditions := g.Conditions()
+		assert.Len(t, conditions, 2)
+		assert.True(t, conditions[0].Matched)
+		assert.Equal(t, `ConditionalOnProperty("cache.enabled", "true")`, conditions[0].Condition)
+		assert.Equal(t, "property cache.enabled=true", conditions[0].Message)
+		assert.False(t, conditions[1].Matched)
+	})
+
+	t.Run("should report missing property", func(t *testing.T) {
+		g := New(quiet, Provide(newTestFoo, ConditionalOnProperty("cache.enabled", "true")))
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, "property cache.enabled is not set", g.Conditions()[0].Message)
+	})
+
+	t.Run("should register default only if nobody else provided the type", func(t *testing.T) {
+		var foo *testFoo
+		g := New(
+			quiet,
+			Provide(func() *testFoo { return &testFoo{name: "default"} }, ConditionalOnMissing()),
+			Provide(func() *testFoo { return &testFoo{name: "custom"} }),
+			Populate(&foo),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, "custom", foo.name)
+		assert.False(t, g.Conditions()[0].Matched)
+		assert.Contains(t, g.Conditions()[0].Message, "*goat.testFoo is already provided by")
+	})
+
+	t.Run("should register default if the type is missing", func(t *testing.T) {
+		var foo *testFoo
+		g := New(
+			quiet,
+			Provide(func() *testFoo { return &testFoo{name: "default"} }, ConditionalOnMissing()),
+			Populate(&foo),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, "default", foo.name)
+		assert.Equal(t, "no provider of *goat.testFoo found", g.Conditions()[0].Message)
+	})
+
+	t.Run("should evaluate missing conditions after the other conditional registrations", func(t *testing.T) {
+		var foo *testFoo
+		g := New(
+			quiet,
+			Properties(map[string]string{"cache.enabled": "true"}),
+			Provide(func() *testFoo { return &testFoo{name: "default"} }, ConditionalOnMissing()),
+			Provide(func() *testFoo { return &testFoo{name: "redis"} }, ConditionalOnProperty("cache.enabled", "true")),
+			Populate(&foo),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, "redis", foo.name)
+
+		conditions := g.Conditions()
+		assert.Len(t, conditions, 2)
+		assert.Equal(t, `ConditionalOnProperty("cache.enabled", "true")`, conditions[0].Condition)
+		assert.True(t, conditions[0].Matched)
+		assert.Equal(t, "ConditionalOnMissing()", conditions[1].Condition)
+		assert.False(t, conditions[1].Matched)
+	})
+
+	t.Run("should stop evaluating at the first condition that does not match", func(t *testing.T) {
+		g := New(
+			quiet,
+			Provide(newTestFoo, ConditionalOnProperty("cache.enabled", ""), ConditionalOnMissing()),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Len(t, g.Conditions(), 1)
+	})
+
+	t.Run("should replace only if the condition matches", func(t *testing.T) {
+		for enabled, expected := range map[string]string{"true": "fake", "false": "foo"} {
+			var foo *testFoo
+			g := New(
+				quiet,
+				Properties(map[string]string{"fake.enabled": enabled}),
+				Provide(newTestFoo),
+				Replace(&testFoo{name: "fake"}, ConditionalOnProperty("fake.enabled", "true")),
+				Populate(&foo),
+			)
+			assert.NoError(t, g.Start(context.Background()))
+			assert.Equal(t, expected, foo.name)
+			assert.Contains(t, g.Conditions()[0].Target, "goat.Replace(*goat.testFoo)")
+		}
+	})
+
+	t.Run("should reject conditions on options that do not register providers", func(t *testing.T) {
+		var foo *testFoo
+		for name, opt := range map[string]Option{
+			"goat.Invoke":   Invoke(func() {}, ConditionalOnProperty("a", "")),
+			"goat.Populate": Populate(&foo, ConditionalOnMissing()),
+			"goat.Decorate": Decorate(func(f *testFoo) *testFoo { return f }, ConditionalOnProperty("a", "")),
+		} {
+			g := New(quiet, Provide(newTestFoo), opt)
+			err := g.Start(context.Background())
+			assert.ErrorContains(t, err, "app > "+name+" does not support")
+			assert.ErrorContains(t, err, "conditions apply to goat.Provide, goat.Override and goat.Replace only")
+		}
+	})
+}
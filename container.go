@@ -0,0 +1,659 @@
+package goat
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	cleanupType = reflect.TypeOf(func() {})
+)
+
+type key struct {
+	t     reflect.Type
+	name  string
+	group string
+}
+
+func (k key) String() string {
+	if k.group != "" {
+		return fmt.Sprintf("[]%v[group=%q]", k.t, k.group)
+	}
+	if k.name != "" {
+		return fmt.Sprintf("%v[name=%q]", k.t, k.name)
+	}
+	return k.t.String()
+}
+
+type result struct {
+	index int
+	field int
+	key   key
+}
+
+type provider struct {
+	fn       reflect.Value
+	name     string
+	location string
+	params   []param
+	results  []result
+	cleanup  int
+	err      int
+	order    int
+	seq      int
+	private  bool
+	override bool
+	scope    *scope
+	called   bool
+	values   []reflect.Value
+	owner    *owner
+	// overriddenBy is the override that took at least one of the provider's keys, so it is not built at all.
+	overriddenBy *provider
+}
+
+type invoker struct {
+	fn       reflect.Value
+	name     string
+	location string
+	params   []param
+	scope    *scope
+}
+
+type scope struct {
+	name       string
+	parent     *scope
+	index      map[key]*provider
+	groups     map[key][]*provider
+	decorators map[key][]*decorator
+	decorated  map[key]reflect.Value
+	decorating map[key]reflect.Value
+}
+
+type pending struct {
+	scope      *scope
+	provider   *provider
+	conditions []condition
+}
+
+type container struct {
+	root       *scope
+	seq        int
+	pending    []pending
+	providers  []*provider
+	built      []*provider
+	invokers   []*invoker
+	decorators []*decorator
+	cleanups   []func()
+	lifecycle  *lifecycle
+	events     *eventBus
+	timeline   *timeline
+	owner      *owner
+}
+
+func newContainer() *container {
+	tl := newTimeline()
+	return &container{
+		root:       newScope("app", nil),
+		pending:    make([]pending, 0),
+		providers:  make([]*provider, 0),
+		built:      make([]*provider, 0),
+		invokers:   make([]*invoker, 0),
+		decorators: make([]*decorator, 0),
+		cleanups:   make([]func(), 0),
+		lifecycle:  newLifecycle(tl),
+		events:     newEventBus(),
+		timeline:   tl,
+	}
+}
+
+func newScope(name string, parent *scope) *scope {
+	return &scope{
+		name:       name,
+		parent:     parent,
+		index:      make(map[key]*provider),
+		groups:     make(map[key][]*provider),
+		decorators: make(map[key][]*decorator),
+		decorated:  make(map[key]reflect.Value),
+		decorating: make(map[key]reflect.Value),
+	}
+}
+
+func (s *scope) path() string {
+	if s.parent == nil {
+		return s.name
+	}
+	return s.parent.path() + " > " + s.name
+}
+
+func newProvider(constructor interface{}, opts provideOptions) (*provider, error) {
+	fn := reflect.ValueOf(constructor)
+	fnType := fn.Type()
+	name, location := funcInfo(fn)
+
+	if opts.name != "" && opts.group != "" {
+		return nil, fmt.Errorf("constructor %s (%s) cannot use goat.Name and goat.Group together", name, location)
+	}
+	params, err := newParams(fnType, opts.paramTags)
+	if err != nil {
+		return nil, fmt.Errorf("constructor %s (%s): %w", name, location, err)
+	}
+
+	numOut := fnType.NumOut()
+	errIndex, cleanupIndex := -1, -1
+	if numOut > 0 && fnType.Out(numOut-1) == errorType {
+		numOut--
+		errIndex = numOut
+	}
+	if numOut > 0 && fnType.Out(numOut-1) == cleanupType {
+		numOut--
+		cleanupIndex = numOut
+	}
+	if numOut == 0 {
+		return nil, fmt.Errorf("constructor %s (%s) must return at least one value", name, location)
+	}
+	results := make([]result, 0, numOut)
+	for i := 0; i < numOut; i++ {
+		t := fnType.Out(i)
+		if t == errorType || t == cleanupType {
+			return nil, fmt.Errorf("constructor %s (%s) must return (T, error) or (T, func(), error), got %v", name, location, fnType)
+		}
+		rs, err := newResults(t, i, opts)
+		if err != nil {
+			return nil, fmt.Errorf("constructor %s (%s): %w", name, location, err)
+		}
+		results = append(results, rs...)
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("constructor %s (%s) must return at least one value", name, location)
+	}
+
+	return &provider{
+		fn:       fn,
+		name:     name,
+		location: location,
+		params:   params,
+		results:  results,
+		cleanup:  cleanupIndex,
+		err:      errIndex,
+		order:    opts.order,
+		private:  opts.private,
+		override: opts.override,
+	}, nil
+}
+
+func newFuncProvider(fn reflect.Value, opts provideOptions, name string, location string) (*provider, error) {
+	p, err := newProvider(fn.Interface(), opts)
+	if err != nil {
+		return nil, err
+	}
+	p.name = name
+	p.location = location
+	return p, nil
+}
+
+func (c *container) provide(s *scope, constructor interface{}, opts provideOptions) error {
+	p, err := newProvider(constructor, opts)
+	if err != nil {
+		return fmt.Errorf("%s > %w", s.path(), err)
+	}
+	return c.add(s, p, opts)
+}
+
+// add registers p, or defers it to Start if it has conditions.
+func (c *container) add(s *scope, p *provider, opts provideOptions) error {
+	if len(opts.conditions) > 0 {
+		p.scope = s
+		p.seq = c.nextSeq()
+		c.pending = append(c.pending, pending{scope: s, provider: p, conditions: opts.conditions})
+		return nil
+	}
+	return c.register(s, p)
+}
+
+func (c *container) nextSeq() int {
+	c.seq++
+	return c.seq
+}
+
+func (c *container) replace(s *scope, value interface{}, opts provideOptions, location string) error {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() {
+		return fmt.Errorf("%s > goat.Replace (%s) expects a non-nil value", s.path(), location)
+	}
+	fn := reflect.MakeFunc(reflect.FuncOf(nil, []reflect.Type{v.Type()}, false), func([]reflect.Value) []reflect.Value {
+		return []reflect.Value{v}
+	})
+	p, err := newFuncProvider(fn, opts, fmt.Sprintf("goat.Replace(%v)", v.Type()), location)
+	if err != nil {
+		return fmt.Errorf("%s > %w", s.path(), err)
+	}
+	return c.add(s, p, opts)
+}
+
+func (c *container) register(s *scope, p *provider) error {
+	p.scope = s
+	target := c.root
+	if p.private {
+		target = s
+	}
+	for _, r := range p.results {
+		if r.key.group != "" {
+			continue
+		}
+		exist, ok := target.index[r.key]
+		if !ok || exist.override != p.override {
+			continue
+		}
+		if p.override {
+			return fmt.Errorf("%s > override of %v by %s (%s) conflicts with override by %s (%s)", s.path(), r.key, p.name, p.location, exist.name, exist.location)
+		}
+		err := fmt.Errorf("%s > %v provided by %s (%s) is already provided by %s (%s)", s.path(), r.key, p.name, p.location, exist.name, exist.location)
+		if r.key.t.Kind() == reflect.Interface && r.key.name == "" {
+			err = fmt.Errorf("%w; qualify one of them with goat.Name", err)
+		}
+		return err
+	}
+	if p.seq == 0 {
+		p.seq = c.nextSeq()
+	}
+	for _, r := range p.results {
+		if r.key.group != "" {
+			target.groups[r.key] = append(target.groups[r.key], p)
+			continue
+		}
+		if exist, ok := target.index[r.key]; ok && exist.override {
+			if p.overriddenBy == nil {
+				p.overriddenBy = exist
+			}
+			continue
+		} else if ok && p.override && exist.overriddenBy == nil {
+			exist.overriddenBy = p
+		}
+		target.index[r.key] = p
+	}
+	c.providers = append(c.providers, p)
+	return nil
+}
+
+func (c *container) active(p *provider) bool {
+	if p.overriddenBy != nil {
+		return false
+	}
+	for _, r := range p.results {
+		if r.key.group != "" || c.owns(p, r.key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *container) owns(p *provider, k key) bool {
+	target := c.root
+	if p.private {
+		target = p.scope
+	}
+	return target.index[k] == p
+}
+
+func (c *container) build() error {
+	if err := c.verify(); err != nil {
+		return err
+	}
+	for _, d := range c.decorators {
+		if len(c.providersOf(d.scope, d.key)) == 0 {
+			return fmt.Errorf("%s > decorator %s (%s) decorates %v which is not provided or not visible to its module", d.scope.path(), d.name, d.location, d.key)
+		}
+	}
+	for _, p := range c.providers {
+		if !c.active(p) {
+			continue
+		}
+		if err := c.call(p); err != nil {
+			return err
+		}
+		for _, r := range p.results {
+			if r.key.group != "" || !c.owns(p, r.key) {
+				continue
+			}
+			if _, err := c.resolve(p.scope, r.key); err != nil {
+				return err
+			}
+		}
+	}
+	for _, i := range c.invokers {
+		if err := c.invoke(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *container) addInvoke(s *scope, fn reflect.Value, name string, location string, opts provideOptions) error {
+	params, err := newParams(fn.Type(), opts.paramTags)
+	if err != nil {
+		return fmt.Errorf("%s > invoke %s (%s): %w", s.path(), name, location, err)
+	}
+	c.invokers = append(c.invokers, &invoker{
+		fn:       fn,
+		name:     name,
+		location: location,
+		params:   params,
+		scope:    s,
+	})
+	return nil
+}
+
+func (c *container) invoke(i *invoker) error {
+	args, err := c.args(i.scope, newOwner(i.scope, i.name, i.params), i.params)
+	if err != nil {
+		return fmt.Errorf("%s > %s (%s) failed: %w", i.scope.path(), i.name, i.location, err)
+	}
+	results := i.fn.Call(args)
+	if n := len(results); n > 0 && i.fn.Type().Out(n-1) == errorType && !results[n-1].IsNil() {
+		return fmt.Errorf("%s > %s (%s) failed: %w", i.scope.path(), i.name, i.location, results[n-1].Interface().(error))
+	}
+	return nil
+}
+
+type dependency struct {
+	scope *scope
+	key   key
+}
+
+// decoratorsOf returns the decorators applied when k is resolved from s, from the module of s up to the one providing k.
+func decoratorsOf(s *scope, k key) []*decorator {
+	if k.group != "" {
+		return nil
+	}
+	decorators := make([]*decorator, 0)
+	for ; s != nil; s = s.parent {
+		decorators = append(decorators, s.decorators[k]...)
+		if _, ok := s.index[k]; ok {
+			break
+		}
+	}
+	return decorators
+}
+
+func (c *container) verify() error {
+	type node struct {
+		key      key
+		verb     string
+		scope    *scope
+		name     string
+		location string
+	}
+	entered := make(map[any]int)
+	visited := make(map[any]bool)
+	path := make([]node, 0)
+
+	var visit func(s *scope, k key) error
+	enter := func(id any, n node, s *scope, params []param) error {
+		if visited[id] {
+			return nil
+		}
+		if i, ok := entered[id]; ok {
+			cycle := append(path[i:], n)
+			names := make([]string, len(cycle))
+			for j, st := range cycle {
+				names[j] = st.key.String()
+			}
+			sb := strings.Builder{}
+			sb.WriteString("dependency cycle detected: ")
+			sb.WriteString(strings.Join(names, " -> "))
+			for _, st := range cycle[:len(cycle)-1] {
+				sb.WriteString(fmt.Sprintf("\n\t%v %s by %s > %s (%s)", st.key, st.verb, st.scope.path(), st.name, st.location))
+			}
+			return fmt.Errorf("%s", sb.String())
+		}
+		entered[id] = len(path)
+		path = append(path, n)
+		for _, dep := range paramDeps(params) {
+			if dep == n.key && n.verb == "decorated" {
+				continue
+			}
+			if err := visit(s, dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		delete(entered, id)
+		visited[id] = true
+		return nil
+	}
+	visit = func(s *scope, k key) error {
+		for _, p := range c.providersOf(s, k) {
+			if p.overriddenBy != nil {
+				continue
+			}
+			if err := enter(p, node{key: k, verb: "provided", scope: p.scope, name: p.name, location: p.location}, p.scope, p.params); err != nil {
+				return err
+			}
+		}
+		for _, d := range decoratorsOf(s, k) {
+			if err := enter(d, node{key: k, verb: "decorated", scope: d.scope, name: d.name, location: d.location}, d.scope, d.params); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, p := range c.providers {
+		for _, r := range p.results {
+			if err := visit(p.scope, r.key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *container) call(p *provider) error {
+	if p.called {
+		return nil
+	}
+
+	p.owner = newOwner(p.scope, p.name, p.params)
+	args, err := c.args(p.scope, p.owner, p.params)
+	if err != nil {
+		return fmt.Errorf("%s > %s (%s) failed: %w", p.scope.path(), p.name, p.location, err)
+	}
+
+	begin := time.Now()
+	results := p.fn.Call(args)
+	c.timeline.record("constructor", p.scope.path()+" > "+p.name, begin)
+	if p.cleanup >= 0 && !results[p.cleanup].IsNil() {
+		c.cleanups = append(c.cleanups, results[p.cleanup].Interface().(func()))
+	}
+	if p.err >= 0 && !results[p.err].IsNil() {
+		err := withExitCode(results[p.err].Interface().(error), ExitCodeDependencyUnavailable)
+		return fmt.Errorf("%s > %s (%s) failed: %w", p.scope.path(), p.name, p.location, err)
+	}
+	p.values = results
+	p.called = true
+	c.built = append(c.built, p)
+	for _, h := range discoverHooks(p) {
+		c.addHook(p.owner, h)
+	}
+	return nil
+}
+
+func (c *container) args(s *scope, owner *owner, params []param) ([]reflect.Value, error) {
+	args := make([]reflect.Value, len(params))
+	for i, p := range params {
+		prev := c.owner
+		c.owner = owner
+		v, err := p.build(c, s)
+		c.owner = prev
+		if err != nil {
+			return nil, fmt.Errorf("parameter %d (%v): %w", i, p, err)
+		}
+		args[i] = v
+	}
+	return args, nil
+}
+
+func (c *container) close() {
+	for i := len(c.cleanups) - 1; i >= 0; i-- {
+		c.cleanups[i]()
+	}
+	c.cleanups = c.cleanups[:0]
+}
+
+func (c *container) resolve(s *scope, k key) (reflect.Value, error) {
+	if k.group != "" {
+		return c.resolveGroup(s, k)
+	}
+	if v, ok := s.decorating[k]; ok {
+		return v, nil
+	}
+	if v, ok := s.decorated[k]; ok {
+		return v, nil
+	}
+
+	var base reflect.Value
+	if p, ok := s.index[k]; ok {
+		if o := p.overriddenBy; o != nil {
+			return reflect.Value{}, fmt.Errorf("%v is provided by %s (%s) which is overridden by %s (%s); override %v as well", k, p.name, p.location, o.name, o.location, k)
+		}
+		if err := c.call(p); err != nil {
+			return reflect.Value{}, err
+		}
+		base = p.value(k)
+	} else if s.parent != nil {
+		v, err := c.resolve(s.parent, k)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		base = v
+	} else {
+		return reflect.Value{}, fmt.Errorf("missing dependency %v", k)
+	}
+
+	v, err := c.applyDecorators(s, k, base)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	s.decorated[k] = v
+	return v, nil
+}
+
+func (c *container) resolveGroup(s *scope, k key) (reflect.Value, error) {
+	members := c.providersOf(s, k)
+	values := reflect.MakeSlice(reflect.SliceOf(k.t), 0, len(members))
+	for _, p := range members {
+		if err := c.call(p); err != nil {
+			return reflect.Value{}, err
+		}
+		values = reflect.Append(values, p.value(k))
+	}
+	return values, nil
+}
+
+func (c *container) providersOf(s *scope, k key) []*provider {
+	if k.group != "" {
+		members := make([]*provider, 0)
+		for ; s != nil; s = s.parent {
+			for _, p := range s.groups[k] {
+				if p.overriddenBy == nil {
+					members = append(members, p)
+				}
+			}
+		}
+		sort.SliceStable(members, func(i, j int) bool {
+			if members[i].order != members[j].order {
+				return members[i].order < members[j].order
+			}
+			return members[i].seq < members[j].seq
+		})
+		return members
+	}
+	for ; s != nil; s = s.parent {
+		if p, ok := s.index[k]; ok {
+			return []*provider{p}
+		}
+	}
+	return nil
+}
+
+func (p *provider) value(k key) reflect.Value {
+	for _, r := range p.results {
+		if r.key != k {
+			continue
+		}
+		if r.field >= 0 {
+			return p.values[r.index].Field(r.field)
+		}
+		return p.values[r.index]
+	}
+	return reflect.Value{}
+}
+
+func containsKey(keys []key, k key) bool {
+	for _, v := range keys {
+		if v == k {
+			return true
+		}
+	}
+	return false
+}
+
+func funcInfo(fn reflect.Value) (name string, location string) {
+	f := runtime.FuncForPC(fn.Pointer())
+	if f == nil {
+		return fn.Type().String(), "unknown"
+	}
+	file, line := f.FileLine(f.Entry())
+	return f.Name(), fmt.Sprintf("%s:%d", file, line)
+}
+
+// owner is the provider, decorator or invoker whose parameters are being built.
+// The hooks it registers depend on the hooks registered by everything it depends on.
+type owner struct {
+	name  string
+	deps  []dependency
+	hooks []*hook
+}
+
+func newOwner(s *scope, name string, params []param) *owner {
+	o := &owner{name: s.path() + " > " + name}
+	for _, k := range paramDeps(params) {
+		o.deps = append(o.deps, dependency{scope: s, key: k})
+	}
+	return o
+}
+
+func (c *container) addHook(o *owner, h *hook) {
+	h.deps = append(c.hookDeps(o.deps), o.hooks...)
+	o.hooks = append(o.hooks, h)
+	c.lifecycle.append(h)
+}
+
+// hookDeps collects the hooks registered by the providers and decorators that deps resolve to, transitively.
+func (c *container) hookDeps(deps []dependency) []*hook {
+	visited := make(map[*owner]bool)
+	hooks := make([]*hook, 0)
+	var visit func(o *owner)
+	visit = func(o *owner) {
+		if o == nil || visited[o] {
+			return
+		}
+		visited[o] = true
+		for _, d := range o.deps {
+			for _, p := range c.providersOf(d.scope, d.key) {
+				visit(p.owner)
+			}
+			for _, dec := range decoratorsOf(d.scope, d.key) {
+				visit(dec.owner)
+			}
+		}
+		hooks = append(hooks, o.hooks...)
+	}
+	visit(&owner{deps: deps})
+	return hooks
+}
@@ -0,0 +1,319 @@
+package goat
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"testing"
+)
+
+type testFoo struct {
+	name string
+}
+
+type testBar struct {
+	foo *testFoo
+}
+
+type testBaz struct {
+	bar *testBar
+}
+
+func newTestFoo() *testFoo {
+	return &testFoo{name: "foo"}
+}
+
+func newTestBar(foo *testFoo) *testBar {
+	return &testBar{foo: foo}
+}
+
+func newTestBaz(bar *testBar) *testBaz {
+	return &testBaz{bar: bar}
+}
+
+func Test_Container(t *testing.T) {
+	t.Run("should build every constructor once", func(t *testing.T) {
+		calls := 0
+		newFoo := func() *testFoo {
+			calls++
+			return newTestFoo()
+		}
+		c := newContainer()
+		assert.NoError(t, c.provide(c.root, newTestBaz, provideOptions{}))
+		assert.NoError(t, c.provide(c.root, newTestBar, provideOptions{}))
+		assert.NoError(t, c.provide(c.root, newFoo, provideOptions{}))
+
+		assert.NoError(t, c.build())
+		assert.Equal(t, 1, calls)
+
+		v, err := c.resolve(c.root, key{t: typeOf[*testBaz]()})
+		assert.NoError(t, err)
+		baz := v.Interface().(*testBaz)
+		assert.Equal(t, "foo", baz.bar.foo.name)
+	})
+
+	t.Run("should return error naming the constructor and parameter if dependency is missing", func(t *testing.T) {
+		c := newContainer()
+		assert.NoError(t, c.provide(c.root, newTestBar, provideOptions{}))
+
+		err := c.build()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "newTestBar")
+		assert.Contains(t, err.Error(), "parameter 0 (*goat.testFoo)")
+	})
+
+	t.Run("should return error if type is provided twice", func(t *testing.T) {
+		c := newContainer()
+		assert.NoError(t, c.provide(c.root, newTestFoo, provideOptions{}))
+		assert.Error(t, c.provide(c.root, newTestFoo, provideOptions{}))
+	})
+
+	t.Run("should return error if constructor returns nothing", func(t *testing.T) {
+		c := newContainer()
+		assert.Error(t, c.provide(c.root, func() {}, provideOptions{}))
+	})
+}
+
+func Test_Goat_Provide(t *testing.T) {
+	t.Run("should build components on start", func(t *testing.T) {
+		var bar *testBar
+		g := New(
+			Provide(newTestFoo, func(foo *testFoo) *testBar {
+				bar = newTestBar(foo)
+				return bar
+			}),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.NotNil(t, bar)
+		assert.Equal(t, "foo", bar.foo.name)
+	})
+
+	t.Run("should fail start if dependency is missing", func(t *testing.T) {
+		g := New(Provide(newTestBar))
+		assert.Error(t, g.Start(context.Background()))
+		assert.Error(t, g.err)
+	})
+
+	t.Run("should panic if constructor is not a function", func(t *testing.T) {
+		assert.Panics(t, func() {
+			Provide("not a function")
+		})
+	})
+
+	t.Run("should provide decoded configuration", func(t *testing.T) {
+		type appConfiguration struct {
+			Name string `properties:"app.name"`
+		}
+		var cfg appConfiguration
+		g := New(
+			Configuration(appConfiguration{}),
+			Provide(func(c appConfiguration) *testFoo {
+				cfg = c
+				return newTestFoo()
+			}),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, "Goat", cfg.Name)
+	})
+}
+
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func Test_Container_Cycle(t *testing.T) {
+	t.Run("should return error with the whole cycle before building anything", func(t *testing.T) {
+		called := false
+		c := newContainer()
+		assert.NoError(t, c.provide(c.root, func(bar *testBar) *testFoo {
+			called = true
+			return &testFoo{}
+		}, provideOptions{}))
+		assert.NoError(t, c.provide(c.root, newTestBar, provideOptions{}))
+
+		err := c.build()
+		assert.Error(t, err)
+		assert.False(t, called)
+		assert.Contains(t, err.Error(), "*goat.testFoo -> *goat.testBar -> *goat.testFoo")
+		assert.Contains(t, err.Error(), "newTestBar")
+		assert.Contains(t, err.Error(), "container_test.go:")
+	})
+
+	t.Run("should detect self dependency", func(t *testing.T) {
+		c := newContainer()
+		assert.NoError(t, c.provide(c.root, func(foo *testFoo) *testFoo { return foo }, provideOptions{}))
+		err := c.build()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "*goat.testFoo -> *goat.testFoo")
+	})
+
+	t.Run("should not report shared dependency as cycle", func(t *testing.T) {
+		c := newContainer()
+		assert.NoError(t, c.provide(c.root, newTestFoo, provideOptions{}))
+		assert.NoError(t, c.provide(c.root, newTestBar, provideOptions{}))
+		assert.NoError(t, c.provide(c.root, func(foo *testFoo, bar *testBar) *testBaz {
+			return &testBaz{bar: bar}
+		}, provideOptions{}))
+		assert.NoError(t, c.build())
+	})
+}
+
+func Test_Container_Results(t *testing.T) {
+	t.Run("should accept (T, error) and (T, func(), error) constructors", func(t *testing.T) {
+		c := newContainer()
+		assert.NoError(t, c.provide(c.root, func() (*testFoo, error) { return newTestFoo(), nil }, provideOptions{}))
+		assert.NoError(t, c.provide(c.root, func(foo *testFoo) (*testBar, func(), error) {
+			return newTestBar(foo), func() {}, nil
+		}, provideOptions{}))
+		assert.NoError(t, c.build())
+		assert.Len(t, c.cleanups, 1)
+	})
+
+	t.Run("should abort build if constructor returns error", func(t *testing.T) {
+		called := false
+		c := newContainer()
+		assert.NoError(t, c.provide(c.root, func() (*testFoo, error) { return nil, errors.New("connection refused") }, provideOptions{}))
+		assert.NoError(t, c.provide(c.root, func(foo *testFoo) *testBar {
+			called = true
+			return newTestBar(foo)
+		}, provideOptions{}))
+		err := c.build()
+		assert.ErrorContains(t, err, "connection refused")
+		assert.False(t, called)
+	})
+
+	t.Run("should run cleanups in reverse order", func(t *testing.T) {
+		order := make([]string, 0)
+		c := newContainer()
+		assert.NoError(t, c.provide(c.root, func() (*testFoo, func()) {
+			return newTestFoo(), func() { order = append(order, "foo") }
+		}, provideOptions{}))
+		assert.NoError(t, c.provide(c.root, func(foo *testFoo) (*testBar, func(), error) {
+			return newTestBar(foo), func() { order = append(order, "bar") }, nil
+		}, provideOptions{}))
+		assert.NoError(t, c.build())
+		c.close()
+		c.close()
+		assert.Equal(t, []string{"bar", "foo"}, order)
+	})
+
+	t.Run("should reject error only constructor", func(t *testing.T) {
+		c := newContainer()
+		assert.Error(t, c.provide(c.root, func() error { return nil }, provideOptions{}))
+	})
+}
+
+func Test_Goat_Cleanup(t *testing.T) {
+	t.Run("should run cleanups on stop after start fails halfway", func(t *testing.T) {
+		cleaned := false
+		g := New(Provide(
+			func() (*testFoo, func(), error) { return newTestFoo(), func() { cleaned = true }, nil },
+			func(foo *testFoo) (*testBar, error) { return nil, errors.New("failed") },
+		))
+		err := g.Start(context.Background())
+		assert.ErrorContains(t, err, "failed")
+		assert.Equal(t, err, g.err)
+		assert.NoError(t, g.Stop(context.Background()))
+		assert.True(t, cleaned)
+	})
+}
+
+type testRealFooService struct {
+	testHookService
+}
+
+func (s *testRealFooService) Name() string {
+	return s.name
+}
+
+func Test_Goat_Override(t *testing.T) {
+	t.Run("should replace provided component with value", func(t *testing.T) {
+		var bar *testBar
+		called := false
+		g := New(
+			Replace(&testFoo{name: "fake"}),
+			Provide(func() *testFoo {
+				called = true
+				return newTestFoo()
+			}),
+			Provide(newTestBar),
+			Invoke(func(b *testBar) { bar = b }),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.False(t, called)
+		assert.Equal(t, "fake", bar.foo.name)
+	})
+
+	t.Run("should override provided component with constructor", func(t *testing.T) {
+		var bar *testBar
+		g := New(
+			Provide(newTestFoo, newTestBar),
+			Override(func() *testFoo { return &testFoo{name: "override"} }),
+			Invoke(func(b *testBar) { bar = b }),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, "override", bar.foo.name)
+	})
+
+	t.Run("should replace interface binding", func(t *testing.T) {
+		var service testFooService
+		g := New(
+			Provide(newTestFoo, As(new(testFooService))),
+			Replace(&testFoo{name: "fake"}, As(new(testFooService))),
+			Invoke(func(s testFooService) { service = s }),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, "fake", service.Name())
+	})
+
+	t.Run("should not build a constructor if an override takes one of its results", func(t *testing.T) {
+		r := &testRecorder{}
+		var service testFooService
+		g := New(
+			Provide(func() *testRealFooService {
+				r.add("real constructor")
+				return &testRealFooService{testHookService{name: "real", recorder: r}}
+			}, As(new(testFooService))),
+			Replace(&testFoo{name: "fake"}, As(new(testFooService))),
+			Populate(&service),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, "fake", service.Name())
+		assert.Empty(t, r.events)
+	})
+
+	t.Run("should ignore the failure of a constructor taken over by an override", func(t *testing.T) {
+		g := New(
+			Provide(func() (*testRealFooService, error) { return nil, errors.New("db unreachable") }, As(new(testFooService))),
+			Override(func() testFooService { return &testFoo{name: "fake"} }),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+	})
+
+	t.Run("should fail start if another result of an overridden constructor is needed", func(t *testing.T) {
+		g := New(
+			Provide(func() (*testFoo, *testDB) { return newTestFoo(), &testDB{dsn: "real"} }),
+			Replace(&testDB{dsn: "fake"}),
+			Invoke(func(f *testFoo, d *testDB) {}),
+		)
+		err := g.Start(context.Background())
+		assert.ErrorContains(t, err, "*goat.testFoo is provided by github.com/PCloud63514/goat.Test_Goat_Override")
+		assert.ErrorContains(t, err, "which is overridden by goat.Replace(*goat.testDB)")
+		assert.ErrorContains(t, err, "override *goat.testFoo as well")
+	})
+
+	t.Run("should fail start if two overrides conflict", func(t *testing.T) {
+		g := New(
+			Provide(newTestFoo),
+			Replace(&testFoo{name: "a"}),
+			Override(func() *testFoo { return &testFoo{name: "b"} }),
+		)
+		assert.ErrorContains(t, g.Start(context.Background()), "conflicts with override by goat.Replace(*goat.testFoo)")
+	})
+
+	t.Run("should fail start if replaced value is nil", func(t *testing.T) {
+		g := New(Replace(nil))
+		assert.ErrorContains(t, g.Start(context.Background()), "expects a non-nil value")
+	})
+}
@@ -0,0 +1,80 @@
+package goat
+
+import (
+	"fmt"
+	"reflect"
+)
+
+type decorator struct {
+	fn       reflect.Value
+	name     string
+	location string
+	params   []param
+	key      key
+	err      int
+	scope    *scope
+	owner    *owner
+}
+
+func newDecorator(function interface{}, opts provideOptions) (*decorator, error) {
+	fn := reflect.ValueOf(function)
+	fnType := fn.Type()
+	name, location := funcInfo(fn)
+
+	params, err := newParams(fnType, opts.paramTags)
+	if err != nil {
+		return nil, fmt.Errorf("decorator %s (%s): %w", name, location, err)
+	}
+
+	numOut := fnType.NumOut()
+	errIndex := -1
+	if numOut > 0 && fnType.Out(numOut-1) == errorType {
+		numOut--
+		errIndex = numOut
+	}
+	if numOut != 1 {
+		return nil, fmt.Errorf("decorator %s (%s) must return (T) or (T, error), got %v", name, location, fnType)
+	}
+	k := key{t: fnType.Out(0), name: opts.name}
+	if !containsKey(paramDeps(params), k) {
+		return nil, fmt.Errorf("decorator %s (%s) must take the %v it decorates as a parameter", name, location, k)
+	}
+
+	return &decorator{
+		fn:       fn,
+		name:     name,
+		location: location,
+		params:   params,
+		key:      k,
+		err:      errIndex,
+	}, nil
+}
+
+func (c *container) decorate(s *scope, function interface{}, opts provideOptions) error {
+	d, err := newDecorator(function, opts)
+	if err != nil {
+		return fmt.Errorf("%s > %w", s.path(), err)
+	}
+	d.scope = s
+	s.decorators[d.key] = append(s.decorators[d.key], d)
+	c.decorators = append(c.decorators, d)
+	return nil
+}
+
+func (c *container) applyDecorators(s *scope, k key, v reflect.Value) (reflect.Value, error) {
+	for _, d := range s.decorators[k] {
+		s.decorating[k] = v
+		d.owner = newOwner(s, d.name, d.params)
+		args, err := c.args(s, d.owner, d.params)
+		delete(s.decorating, k)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("%s > %s (%s) failed: %w", s.path(), d.name, d.location, err)
+		}
+		results := d.fn.Call(args)
+		if d.err >= 0 && !results[d.err].IsNil() {
+			return reflect.Value{}, fmt.Errorf("%s > %s (%s) failed: %w", s.path(), d.name, d.location, results[d.err].Interface().(error))
+		}
+		v = results[0]
+	}
+	return v, nil
+}
@@ -0,0 +1,95 @@
+package goat
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_Goat_Decorate(t *testing.T) {
+	t.Run("should inject decorated component instead of the original", func(t *testing.T) {
+		var bar *testBar
+		g := New(
+			Provide(newTestFoo),
+			Decorate(func(foo *testFoo, db *testDB) *testFoo {
+				return &testFoo{name: foo.name + "+" + db.dsn}
+			}),
+			Provide(func() *testDB { return &testDB{dsn: "db"} }),
+			Provide(func(foo *testFoo) *testBar {
+				bar = newTestBar(foo)
+				return bar
+			}),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, "foo+db", bar.foo.name)
+	})
+
+	t.Run("should apply decorators in registration order", func(t *testing.T) {
+		var foo *testFoo
+		g := New(
+			Provide(newTestFoo),
+			Decorate(func(f *testFoo) *testFoo { return &testFoo{name: f.name + "-a"} }),
+			Decorate(func(f *testFoo) (*testFoo, error) { return &testFoo{name: f.name + "-b"}, nil }),
+			Invoke(func(f *testFoo) { foo = f }),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, "foo-a-b", foo.name)
+	})
+
+	t.Run("should decorate named component", func(t *testing.T) {
+		var db *testDB
+		g := New(
+			Provide(func() *testDB { return &testDB{dsn: "primary"} }, Name("primary")),
+			Decorate(func(d *testDB) *testDB { return &testDB{dsn: d.dsn + "-logged"} }, Name("primary"), ParamTags(`name:"primary"`)),
+			Invoke(func(d *testDB) { db = d }, ParamTags(`name:"primary"`)),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, "primary-logged", db.dsn)
+	})
+
+	t.Run("should abort start if decorator returns error", func(t *testing.T) {
+		g := New(
+			Provide(newTestFoo),
+			Decorate(func(f *testFoo) (*testFoo, error) { return nil, errors.New("decorate failed") }),
+		)
+		assert.ErrorContains(t, g.Start(context.Background()), "decorate failed")
+	})
+
+	t.Run("should fail start if decorated type is not provided", func(t *testing.T) {
+		g := New(Decorate(func(f *testFoo) *testFoo { return f }))
+		assert.ErrorContains(t, g.Start(context.Background()), "which is not provided")
+	})
+
+	t.Run("should fail if decorator does not take the decorated type", func(t *testing.T) {
+		g := New(
+			Provide(newTestFoo),
+			Decorate(func() *testFoo { return &testFoo{} }),
+		)
+		assert.ErrorContains(t, g.Start(context.Background()), "must take the *goat.testFoo it decorates")
+	})
+
+	t.Run("should detect cycle through decorator dependencies", func(t *testing.T) {
+		g := New(
+			Provide(newTestFoo, newTestBar),
+			Decorate(func(f *testFoo, b *testBar) *testFoo { return f }),
+		)
+		assert.ErrorContains(t, g.Start(context.Background()), "dependency cycle detected")
+	})
+
+	t.Run("should not detect cycle through decorator of another module", func(t *testing.T) {
+		var foo *testFoo
+		g := New(
+			Provide(newTestFoo, newTestBar),
+			Module("b",
+				Decorate(func(f *testFoo, b *testBar) *testFoo { return &testFoo{name: f.name + "+" + b.foo.name} }),
+				Provide(func(f *testFoo) *testBaz {
+					foo = f
+					return &testBaz{}
+				}),
+			),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, "foo+foo", foo.name)
+	})
+}
@@ -0,0 +1,100 @@
+package goat
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+var (
+	dumpArgs       = regexp.MustCompile(`\([^()]*\)$`)
+	dumpOffset     = regexp.MustCompile(` \+0x[0-9a-f]+$`)
+	dumpCreatedBy  = regexp.MustCompile(` in goroutine \d+$`)
+	dumpStateTimer = regexp.MustCompile(`, \d+ minutes?`)
+)
+
+type goroutineGroup struct {
+	ids    []string
+	states map[string]bool
+	stack  string
+}
+
+// dumpGoroutines writes the stack of every goroutine to the configured dump file or stderr.
+func (g *Goat) dumpGoroutines(reason string) {
+	var w io.Writer = os.Stderr
+	target := "stderr"
+	if g.settings.dumpFile != "" {
+		f, err := os.Create(g.settings.dumpFile)
+		if err != nil {
+			g.logger.Printf("could not create goroutine dump file %s, writing to stderr: %v", g.settings.dumpFile, err)
+		} else {
+			defer f.Close()
+			w, target = f, g.settings.dumpFile
+		}
+	}
+	g.logger.Printf("%s, writing goroutine dump to %s", reason, target)
+	fmt.Fprintf(w, "goroutine dump: %s\n\n", reason)
+	writeGoroutines(w, allStacks())
+}
+
+func allStacks() string {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return string(buf[:n])
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+}
+
+// writeGoroutines groups the goroutines of a runtime.Stack dump by identical stacks,
+// ignoring argument values and offsets, and writes the largest groups first.
+func writeGoroutines(w io.Writer, dump string) {
+	groups := make(map[string]*goroutineGroup)
+	order := make([]*goroutineGroup, 0)
+	for _, stack := range strings.Split(strings.TrimSpace(dump), "\n\n") {
+		header, frames, _ := strings.Cut(stack, "\n")
+		fields := strings.Fields(header)
+		if len(fields) < 3 || fields[0] != "goroutine" {
+			continue
+		}
+		state := strings.TrimSuffix(strings.Join(fields[2:], " "), ":")
+		state = dumpStateTimer.ReplaceAllString(state, "")
+
+		lines := strings.Split(frames, "\n")
+		for i, line := range lines {
+			if strings.HasPrefix(line, "\t") {
+				lines[i] = dumpOffset.ReplaceAllString(line, "")
+			} else {
+				lines[i] = dumpCreatedBy.ReplaceAllString(dumpArgs.ReplaceAllString(line, "(...)"), "")
+			}
+		}
+		normalized := strings.Join(lines, "\n")
+
+		group, ok := groups[normalized]
+		if !ok {
+			group = &goroutineGroup{states: make(map[string]bool), stack: normalized}
+			groups[normalized] = group
+			order = append(order, group)
+		}
+		group.ids = append(group.ids, fields[1])
+		group.states[state] = true
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return len(order[i].ids) > len(order[j].ids)
+	})
+	for _, group := range order {
+		states := make([]string, 0, len(group.states))
+		for state := range group.states {
+			states = append(states, state)
+		}
+		sort.Strings(states)
+		fmt.Fprintf(w, "%d goroutine(s) %s: %s\n%s\n\n", len(group.ids), strings.Join(states, " "), strings.Join(group.ids, ", "), group.stack)
+	}
+}
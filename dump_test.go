@@ -0,0 +1,25 @@
+package goat
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func Test_WriteGoroutines(t *testing.T) {
+	t.Run("should group goroutines with identical stacks", func(t *testing.T) {
+		dump := strings.Join([]string{
+			"goroutine 1 [running]:\nmain.main()\n\t/app/main.go:10 +0x1d",
+			"goroutine 7 [chan receive, 5 minutes]:\nmain.worker(0xc000010000)\n\t/app/main.go:20 +0x25\ncreated by main.main in goroutine 1\n\t/app/main.go:12 +0x30",
+			"goroutine 8 [chan receive]:\nmain.worker(0xc000010040)\n\t/app/main.go:20 +0x25\ncreated by main.main in goroutine 1\n\t/app/main.go:12 +0x30",
+		}, "\n\n")
+
+		buf := &bytes.Buffer{}
+		writeGoroutines(buf, dump)
+		assert.Equal(t, "2 goroutine(s) [chan receive]: 7, 8\n"+
+			"main.worker(...)\n\t/app/main.go:20\ncreated by main.main\n\t/app/main.go:12\n\n"+
+			"1 goroutine(s) [running]: 1\n"+
+			"main.main(...)\n\t/app/main.go:10\n\n", buf.String())
+	})
+}
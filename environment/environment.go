@@ -35,6 +35,7 @@ type Environment struct {
 type Option struct {
 	ResPath        string
 	Profiles       []string
+	Properties     map[string]string
 	Configurations []interface{}
 }
 
@@ -45,6 +46,9 @@ func (opt *Option) apply(option Option) {
 	if option.Profiles != nil && len(option.Profiles) > 0 {
 		opt.Profiles = option.Profiles
 	}
+	if option.Properties != nil && len(option.Properties) > 0 {
+		opt.Properties = option.Properties
+	}
 	if option.Configurations != nil && len(option.Configurations) > 0 {
 		opt.Configurations = option.Configurations
 	}
@@ -83,6 +87,7 @@ func new(opt *Option) *Environment {
 		}
 		sources[profile] = source
 	}
+	mergeMap(rootPropertySource.resource, opt.Properties)
 	prop := properties.LoadMap(rootPropertySource.resource)
 	for _, instance := range opt.Configurations {
 		if err := prop.Decode(instance); err != nil {
@@ -148,3 +148,26 @@ func TestEnvironment_ContainsProperty(t *testing.T) {
 		}
 	})
 }
+
+func TestEnvironment_Properties(t *testing.T) {
+	env := New(Option{
+		ResPath:  ".",
+		Profiles: []string{"test"},
+		Properties: map[string]string{
+			"test.value.string": "override",
+			"test.value.extra":  "extra",
+		},
+	})
+	t.Run("주입한 프로퍼티가 리소스보다 우선합니다.", func(t *testing.T) {
+		v := env.GetProperty("test.value.string", "")
+		if v != "override" {
+			t.Errorf("주입한 프로퍼티 값과 동일하지 않습니다. \nExpected: %v\nActual: %v", "override", v)
+		}
+	})
+	t.Run("주입한 프로퍼티를 반환합니다.", func(t *testing.T) {
+		v := env.GetProperty("test.value.extra", "")
+		if v != "extra" {
+			t.Errorf("주입한 프로퍼티 값과 동일하지 않습니다. \nExpected: %v\nActual: %v", "extra", v)
+		}
+	})
+}
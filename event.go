@@ -0,0 +1,142 @@
+package goat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+)
+
+var eventsType = reflect.TypeOf((*Events)(nil)).Elem()
+
+// ProfileActivatedEvent is published on Goat.Start once every component is built, with the active profiles.
+type ProfileActivatedEvent struct {
+	Profiles []string
+}
+
+// StartedEvent is published on Goat.Start once every OnStart hook succeeded.
+type StartedEvent struct {
+	StartUpDateTime time.Time
+	Duration        time.Duration
+}
+
+// ReadyEvent is published on Goat.Start after the synchronous StartedEvent listeners finished.
+type ReadyEvent struct {
+	StartUpDateTime time.Time
+	Duration        time.Duration
+}
+
+// StoppingEvent is published on Goat.Stop of a started app, before any OnStop hook runs.
+type StoppingEvent struct{}
+
+// Events is injected into constructors and invoke functions to publish events and subscribe to them
+// with Subscribe and SubscribeAsync.
+type Events interface {
+	// Publish calls the listeners of the event's type in subscription order. Listeners subscribed to an interface
+	// receive every event implementing it. Publish returns the errors of the synchronous listeners.
+	Publish(ctx context.Context, event any) error
+	subscribe(l *listener)
+}
+
+// Subscribe registers fn to be called on Publish of events of type E, before Publish returns.
+// A returned error is returned by Publish, and fails Goat.Start for the events goat publishes on Start.
+func Subscribe[E any](events Events, fn func(ctx context.Context, event E) error) {
+	events.subscribe(newListener(fn, false))
+}
+
+// SubscribeAsync registers fn to be called in its own goroutine on Publish of events of type E.
+// A returned error is logged. Goat.Stop waits for the running listeners.
+func SubscribeAsync[E any](events Events, fn func(ctx context.Context, event E) error) {
+	events.subscribe(newListener(fn, true))
+}
+
+type listener struct {
+	t        reflect.Type
+	name     string
+	location string
+	async    bool
+	call     func(ctx context.Context, event any) error
+}
+
+func newListener[E any](fn func(ctx context.Context, event E) error, async bool) *listener {
+	name, location := funcInfo(reflect.ValueOf(fn))
+	return &listener{
+		t:        reflect.TypeOf((*E)(nil)).Elem(),
+		name:     name,
+		location: location,
+		async:    async,
+		call: func(ctx context.Context, event any) error {
+			return fn(ctx, event.(E))
+		},
+	}
+}
+
+func (l *listener) accepts(t reflect.Type) bool {
+	return t == l.t || (l.t.Kind() == reflect.Interface && t.Implements(l.t))
+}
+
+type eventBus struct {
+	mu        sync.Mutex
+	listeners []*listener
+	running   sync.WaitGroup
+	logger    *log.Logger
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{listeners: make([]*listener, 0)}
+}
+
+func (b *eventBus) subscribe(l *listener) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.listeners = append(b.listeners, l)
+}
+
+func (b *eventBus) Publish(ctx context.Context, event any) error {
+	t := reflect.TypeOf(event)
+	if t == nil {
+		return errors.New("cannot publish a nil event")
+	}
+	b.mu.Lock()
+	listeners := append([]*listener(nil), b.listeners...)
+	b.mu.Unlock()
+
+	errs := make([]error, 0)
+	for _, l := range listeners {
+		if !l.accepts(t) {
+			continue
+		}
+		if l.async {
+			b.running.Add(1)
+			go func() {
+				defer b.running.Done()
+				if err := l.call(context.WithoutCancel(ctx), event); err != nil {
+					b.logger.Printf("event %v listener %s (%s) failed: %v", t, l.name, l.location, err)
+				}
+			}()
+			continue
+		}
+		if err := l.call(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("event %v listener %s (%s) failed: %w", t, l.name, l.location, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// wait blocks until the asynchronous listeners finished or ctx is done.
+func (b *eventBus) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		b.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("asynchronous event listeners did not finish: %w", ctx.Err())
+	}
+}
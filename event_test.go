@@ -0,0 +1,142 @@
+package goat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type testOrderPlaced struct {
+	id string
+}
+
+func (e testOrderPlaced) String() string {
+	return "order " + e.id
+}
+
+func Test_Events(t *testing.T) {
+	t.Run("should call listeners of the published type in subscription order", func(t *testing.T) {
+		r := &testRecorder{}
+		g := New(Invoke(func(events Events) error {
+			Subscribe(events, func(ctx context.Context, e testOrderPlaced) error {
+				r.add("first " + e.id)
+				return nil
+			})
+			Subscribe(events, func(ctx context.Context, e fmt.Stringer) error {
+				r.add("stringer " + e.String())
+				return nil
+			})
+			Subscribe(events, func(ctx context.Context, e *testOrderPlaced) error {
+				r.add("pointer " + e.id)
+				return nil
+			})
+			return events.Publish(context.Background(), testOrderPlaced{id: "1"})
+		}))
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, []string{"first 1", "stringer order 1"}, r.events)
+	})
+
+	t.Run("should return the errors of synchronous listeners", func(t *testing.T) {
+		var err error
+		g := New(Invoke(func(events Events) {
+			Subscribe(events, func(ctx context.Context, e testOrderPlaced) error { return errors.New("out of stock") })
+			err = events.Publish(context.Background(), testOrderPlaced{id: "1"})
+		}))
+		assert.NoError(t, g.Start(context.Background()))
+		assert.ErrorContains(t, err, "event goat.testOrderPlaced listener")
+		assert.ErrorContains(t, err, "out of stock")
+	})
+
+	t.Run("should call asynchronous listeners in their own goroutine and wait for them on stop", func(t *testing.T) {
+		release := make(chan struct{})
+		done := false
+		g := New(Invoke(func(events Events) error {
+			SubscribeAsync(events, func(ctx context.Context, e testOrderPlaced) error {
+				<-release
+				done = true
+				return nil
+			})
+			return events.Publish(context.Background(), testOrderPlaced{id: "1"})
+		}))
+		assert.NoError(t, g.Start(context.Background()))
+		close(release)
+		assert.NoError(t, g.Stop(context.Background()))
+		assert.True(t, done)
+	})
+
+	t.Run("should fail stop if asynchronous listeners exceed the stop timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		g := New(StopTimeout(20*time.Millisecond), Invoke(func(events Events) error {
+			SubscribeAsync(events, func(ctx context.Context, e testOrderPlaced) error {
+				<-release
+				return nil
+			})
+			return events.Publish(context.Background(), testOrderPlaced{id: "1"})
+		}))
+		assert.NoError(t, g.Start(context.Background()))
+		assert.ErrorIs(t, g.Stop(context.Background()), context.DeadlineExceeded)
+	})
+
+	t.Run("should publish lifecycle events", func(t *testing.T) {
+		r := &testRecorder{}
+		var started StartedEvent
+		g := New(
+			Profiles("test"),
+			Invoke(func(events Events, lc Lifecycle) {
+				Subscribe(events, func(ctx context.Context, e ProfileActivatedEvent) error {
+					r.add(fmt.Sprintf("profiles %v", e.Profiles))
+					return nil
+				})
+				Subscribe(events, func(ctx context.Context, e StartedEvent) error {
+					started = e
+					r.add("started")
+					return nil
+				})
+				Subscribe(events, func(ctx context.Context, e ReadyEvent) error {
+					r.add("ready")
+					return nil
+				})
+				Subscribe(events, func(ctx context.Context, e StoppingEvent) error {
+					r.add("stopping")
+					return nil
+				})
+				lc.Append(Hook{OnStart: r.record("start"), OnStop: r.record("stop")})
+			}),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.NoError(t, g.Stop(context.Background()))
+		assert.Equal(t, []string{"profiles [default test]", "start", "started", "ready", "stopping", "stop"}, r.events)
+		assert.Equal(t, g.startUpDateTime, started.StartUpDateTime)
+		assert.Positive(t, started.Duration)
+	})
+
+	t.Run("should not publish stopping if the app did not start", func(t *testing.T) {
+		r := &testRecorder{}
+		subscribe := Invoke(func(events Events) {
+			Subscribe(events, func(ctx context.Context, e StoppingEvent) error {
+				r.add("stopping")
+				return nil
+			})
+		})
+		failed := New(subscribe, Invoke(func() error { return errors.New("boom") }))
+		assert.Error(t, failed.Start(context.Background()))
+		assert.NoError(t, failed.Stop(context.Background()))
+
+		assert.NoError(t, New(subscribe).Stop(context.Background()))
+		assert.Empty(t, r.events)
+	})
+
+	t.Run("should roll back start if a ready listener fails", func(t *testing.T) {
+		r := &testRecorder{}
+		g := New(Invoke(func(events Events, lc Lifecycle) {
+			Subscribe(events, func(ctx context.Context, e ReadyEvent) error { return errors.New("not ready") })
+			lc.Append(Hook{OnStart: r.record("start"), OnStop: r.record("stop")})
+		}))
+		assert.ErrorContains(t, g.Start(context.Background()), "not ready")
+		assert.Equal(t, []string{"start", "stop"}, r.events)
+	})
+}
@@ -0,0 +1,60 @@
+package goat
+
+import (
+	"errors"
+)
+
+// Codes Run exits with for goat's own failures. Any other failure exits with ExitCodeFailure
+// unless its error implements ExitCoder.
+const (
+	// ExitCodeFailure is the code of a failed Start or Stop without a more specific code.
+	ExitCodeFailure = 1
+	// ExitCodeConfigInvalid is the code of a configuration struct or goat.lifecycle property that could not be decoded.
+	ExitCodeConfigInvalid = 2
+	// ExitCodeShutdownTimeout is the code of a Stop that exceeded the app stop timeout or was interrupted by a second signal.
+	// A goroutine dump is written before exiting.
+	ExitCodeShutdownTimeout = 3
+	// ExitCodeDependencyUnavailable is the code of a constructor or OnStart hook that failed, for example because
+	// a database could not be reached. A missing provider is a wiring error and exits with ExitCodeFailure.
+	ExitCodeDependencyUnavailable = 4
+)
+
+// ExitCoder is implemented by errors that decide the code Run exits with when they fail Start or Stop.
+// Within errors.Join and wrapped errors the first ExitCoder found by errors.As wins.
+type ExitCoder interface {
+	ExitCode() int
+}
+
+type exitError struct {
+	err  error
+	code int
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+func (e *exitError) ExitCode() int {
+	return e.code
+}
+
+// withExitCode gives err the exit code unless it already carries one.
+func withExitCode(err error, code int) error {
+	var coder ExitCoder
+	if errors.As(err, &coder) {
+		return err
+	}
+	return &exitError{err: err, code: code}
+}
+
+func exitCodeOf(err error) int {
+	var coder ExitCoder
+	if errors.As(err, &coder) {
+		return coder.ExitCode()
+	}
+	return ExitCodeFailure
+}
@@ -0,0 +1,85 @@
+package goat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log"
+	"os"
+	"syscall"
+	"testing"
+)
+
+type testExitError struct {
+	code int
+}
+
+func (e testExitError) Error() string {
+	return fmt.Sprintf("exit %d", e.code)
+}
+
+func (e testExitError) ExitCode() int {
+	return e.code
+}
+
+func Test_Goat_ExitCode(t *testing.T) {
+	signal := func() <-chan os.Signal {
+		ch := make(chan os.Signal, 1)
+		ch <- syscall.SIGTERM
+		return ch
+	}
+	discard := Logger(log.New(io.Discard, "", 0))
+
+	t.Run("should exit with the code of an ExitCoder failing start", func(t *testing.T) {
+		g := New(discard, Invoke(func(lc Lifecycle) {
+			lc.Append(Hook{OnStart: func(ctx context.Context) error {
+				return fmt.Errorf("broker: %w", testExitError{code: 75})
+			}})
+		}))
+		assert.Equal(t, 75, g.run(signal))
+	})
+
+	t.Run("should exit with the code of an ExitCoder failing stop", func(t *testing.T) {
+		g := New(discard, Invoke(func(lc Lifecycle) {
+			lc.Append(Hook{OnStop: func(ctx context.Context) error { return testExitError{code: 70} }})
+		}))
+		assert.Equal(t, 70, g.run(signal))
+	})
+
+	t.Run("should exit with the config invalid code if a configuration cannot be decoded", func(t *testing.T) {
+		type cfg struct {
+			Missing string `properties:"not.exist"`
+		}
+		g := New(discard, Configuration(cfg{}), Invoke(func(c cfg) {}))
+		assert.Equal(t, ExitCodeConfigInvalid, g.run(signal))
+	})
+
+	t.Run("should exit with the config invalid code if a lifecycle property is invalid", func(t *testing.T) {
+		g := New(discard, Properties(map[string]string{"goat.lifecycle.start-timeout": "soon"}))
+		assert.Equal(t, ExitCodeConfigInvalid, g.run(signal))
+	})
+
+	t.Run("should exit with the dependency unavailable code if a constructor fails", func(t *testing.T) {
+		g := New(discard, Provide(func() (*testDB, error) { return nil, errors.New("connection refused") }))
+		assert.Equal(t, ExitCodeDependencyUnavailable, g.run(signal))
+	})
+
+	t.Run("should exit with the dependency unavailable code if a start hook fails", func(t *testing.T) {
+		g := New(discard, Invoke(func(lc Lifecycle) {
+			lc.Append(Hook{OnStart: func(ctx context.Context) error { return errors.New("broker unreachable") }})
+		}))
+		assert.Equal(t, ExitCodeDependencyUnavailable, g.run(signal))
+	})
+
+	t.Run("should exit with the failure code if a provider is missing", func(t *testing.T) {
+		g := New(discard, Provide(newTestBar))
+		assert.Equal(t, ExitCodeFailure, g.run(signal))
+	})
+
+	t.Run("should exit with the failure code for other errors", func(t *testing.T) {
+		g := New(discard, Invoke(func() error { return errors.New("boom") }))
+		assert.Equal(t, ExitCodeFailure, g.run(signal))
+	})
+}
@@ -2,40 +2,100 @@ package goat
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/PCloud63514/goat/environment"
 	"github.com/PCloud63514/goat/profile"
+	"log"
 	"os"
 	"os/signal"
 	"reflect"
+	"runtime"
 	"syscall"
 	"time"
 )
 
+type state int
+
+const (
+	stateNew state = iota
+	stateStarted
+	stateStopped
+)
+
 type Goat struct {
 	err             error
+	state           state
+	settings        settings
 	startUpDateTime time.Time
 	profile         *profile.Profile
 	environment     *environment.Environment
-	hooks           map[HookType][]HookFunc
+	logger          *log.Logger
+	conditions      []ConditionOutcome
+	container       *container
+	lifecycle       *lifecycle
+	events          *eventBus
+	timeline        *timeline
+	startUpDuration time.Duration
+}
+
+type Option interface {
+	apply(m *module)
 }
 
-type Option interface{}
+type optionFunc func(m *module)
+
+func (f optionFunc) apply(m *module) {
+	f(m)
+}
 
 func New(opts ...Option) *Goat {
-	startUpDateTime := time.Now()
-	prof := profile.New()
-	env := environment.New(environment.Option {
-		Profiles: profile.Get(),
+	g := &Goat{
+		startUpDateTime: time.Now(),
+		container:       newContainer(),
+	}
+	g.lifecycle = g.container.lifecycle
+	g.events = g.container.events
+	g.timeline = g.container.timeline
+	for _, opt := range opts {
+		if setting, ok := opt.(settingOption); ok {
+			setting(&g.settings)
+		}
+	}
+
+	g.logger = g.settings.logger
+	if g.logger == nil {
+		g.logger = log.New(os.Stderr, "[goat] ", log.LstdFlags)
+	}
+	g.events.logger = g.logger
+	g.profile = profile.New()
+	if g.settings.profiles != nil {
+		g.profile = profile.Of(g.settings.profiles...)
+	}
+	g.environment = environment.New(environment.Option{
+		ResPath:    g.settings.resourcePath,
+		Profiles:   g.profile.Get(),
+		Properties: g.settings.properties,
 	})
+	g.lifecycle.startTimeout = g.timeoutSetting(g.settings.startTimeout, startTimeoutProperty)
+	g.lifecycle.stopTimeout = g.timeoutSetting(g.settings.stopTimeout, stopTimeoutProperty)
+	g.lifecycle.concurrency = g.concurrencySetting()
+	if g.settings.dumpFile == "" {
+		g.settings.dumpFile = g.environment.GetProperty(dumpFileProperty, "")
+	}
 
-	return &Goat{
-		startUpDateTime: startUpDateTime,
-		profile:         prof,
-		environment:     env,
-		hooks:           make(map[HookType][]HookFunc),
+	root := &module{goat: g, scope: g.container.root}
+	for _, opt := range opts {
+		if _, ok := opt.(settingOption); ok || opt == nil {
+			continue
+		}
+		opt.apply(root)
 	}
+	return g
 }
 
+// Run starts the app, stops it on SIGINT or SIGTERM and exits the process with a non-zero code if either failed.
+// See ExitCoder for how the code is chosen.
 func (g *Goat) Run() {
 	if exitCode := g.run(g.Wait); exitCode != 0 {
 		os.Exit(exitCode)
@@ -48,15 +108,70 @@ func (g *Goat) Wait() <-chan os.Signal {
 	return ch
 }
 
+// Start builds the components, runs the invoke functions and starts the lifecycle hooks.
+// An app starts once: Start fails if it already started or stopped.
 func (g *Goat) Start(ctx context.Context) (err error) {
 	if g.err != nil {
 		return g.err
 	}
+	switch g.state {
+	case stateStarted:
+		return errors.New("goat: app is already started")
+	case stateStopped:
+		return errors.New("goat: app is stopped and cannot be started again")
+	}
+	if err := g.evaluateConditions(); err != nil {
+		g.err = err
+		return err
+	}
+	if err := g.container.build(); err != nil {
+		g.err = err
+		return err
+	}
+	if err := g.events.Publish(ctx, ProfileActivatedEvent{Profiles: g.profile.Get()}); err != nil {
+		g.err = err
+		return err
+	}
+	if err := g.lifecycle.start(ctx); err != nil {
+		g.err = err
+		return err
+	}
+	g.startUpDuration = time.Since(g.startUpDateTime)
+	g.logger.Printf("%v", g.Timeline())
+	if err := g.publishStarted(ctx); err != nil {
+		if rollbackErr := g.lifecycle.rollback(context.WithoutCancel(ctx)); rollbackErr != nil {
+			err = errors.Join(err, fmt.Errorf("rollback failed: %w", rollbackErr))
+		}
+		g.err = err
+		return err
+	}
+	g.state = stateStarted
 	return nil
 }
 
+func (g *Goat) publishStarted(ctx context.Context) error {
+	if err := g.events.Publish(ctx, StartedEvent{StartUpDateTime: g.startUpDateTime, Duration: g.startUpDuration}); err != nil {
+		return err
+	}
+	return g.events.Publish(ctx, ReadyEvent{StartUpDateTime: g.startUpDateTime, Duration: g.startUpDuration})
+}
+
+// Stop stops the lifecycle hooks and runs the cleanup functions. Stopping a stopped app does nothing.
 func (g *Goat) Stop(ctx context.Context) (err error) {
-	return nil
+	if g.state == stateStopped {
+		return nil
+	}
+	var stoppingErr error
+	if g.state == stateStarted {
+		stoppingErr = g.events.Publish(ctx, StoppingEvent{})
+	}
+	g.state = stateStopped
+	err = g.lifecycle.stop(ctx)
+	waitCtx, cancel := withTimeout(ctx, g.lifecycle.stopTimeout)
+	defer cancel()
+	waitErr := g.events.wait(waitCtx)
+	g.container.close()
+	return errors.Join(stoppingErr, err, waitErr)
 }
 
 func (g *Goat) run(done func() <-chan os.Signal) (exitCode int) {
@@ -64,32 +179,221 @@ func (g *Goat) run(done func() <-chan os.Signal) (exitCode int) {
 	defer startCancel()
 
 	if err := g.Start(startCtx); err != nil {
-		return 1
+		g.shutdown(nil)
+		return exitCodeOf(err)
 	}
 
-	<-done()
+	signals := done()
+	<-signals
 
-	stopCtx, stopCancel := context.WithCancel(context.Background())
+	return g.shutdown(signals)
+}
+
+// shutdown stops the app and dumps every goroutine if the app stop timeout passes or another signal arrives
+// before Stop returns. Otherwise a failed Stop exits with the code of its error.
+func (g *Goat) shutdown(signals <-chan os.Signal) (exitCode int) {
+	stopCtx, stopCancel := withTimeout(context.Background(), g.lifecycle.stopTimeout)
 	defer stopCancel()
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- g.Stop(stopCtx)
+	}()
 
-	if err := g.Stop(stopCtx); err != nil {
-		return 1
+	select {
+	case err := <-stopped:
+		if errors.Is(stopCtx.Err(), context.DeadlineExceeded) {
+			g.dumpGoroutines("stop timed out")
+			return ExitCodeShutdownTimeout
+		}
+		if err != nil {
+			return exitCodeOf(err)
+		}
+		return 0
+	case <-stopCtx.Done():
+		g.dumpGoroutines("stop timed out")
+		return ExitCodeShutdownTimeout
+	case sig := <-signals:
+		g.dumpGoroutines(fmt.Sprintf("received %v while stopping", sig))
+		return ExitCodeShutdownTimeout
 	}
+}
 
-	return 0
+// Provide registers constructors whose results are built once as singletons on Start.
+// ProvideOption arguments such as Name apply to every constructor of the same call.
+func Provide(args ...interface{}) Option {
+	return newOption(args, "constructor", provide)
 }
 
-func Provide(constructors ...interface{}) Option {
+// Override registers constructors that take precedence over Provide registrations of the same type,
+// regardless of registration order. Two overrides of the same type fail Start. A constructor that an override takes
+// any result type from is not built, so its other result types must be overridden too if anything needs them.
+func Override(args ...interface{}) Option {
+	return newOption(args, "constructor", func(m *module, constructors []interface{}, opts provideOptions) error {
+		opts.override = true
+		return provide(m, constructors, opts)
+	})
+}
+
+func provide(m *module, constructors []interface{}, opts provideOptions) error {
 	for _, constructor := range constructors {
-		fnType := reflect.TypeOf(constructor)
-		if fnType.Kind() != reflect.Func {
-			panic("constructor must be a function")
+		if err := m.goat.container.provide(m.scope, constructor, opts); err != nil {
+			return err
 		}
 	}
-
 	return nil
 }
 
+// Replace overrides the Provide registrations of each value's type with the value itself, like Override.
+func Replace(args ...interface{}) Option {
+	_, file, line, _ := runtime.Caller(1)
+	location := fmt.Sprintf("%s:%d", file, line)
+	return newOption(args, "", func(m *module, values []interface{}, opts provideOptions) error {
+		opts.override = true
+		for _, value := range values {
+			if err := m.goat.container.replace(m.scope, value, opts, location); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Invoke registers functions that run on Start once every component is built.
+// Their parameters are injected like constructor parameters and a returned error aborts Start.
+func Invoke(args ...interface{}) Option {
+	return newOption(args, "invoke target", func(m *module, functions []interface{}, opts provideOptions) error {
+		if err := rejectConditions(m, "goat.Invoke", opts); err != nil {
+			return err
+		}
+		for _, function := range functions {
+			fn := reflect.ValueOf(function)
+			name, location := funcInfo(fn)
+			if err := m.goat.container.addInvoke(m.scope, fn, name, location, opts); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Populate sets each target pointer to the component of its element type once every component is built.
+// It is mostly useful in tests to pull components out of the app.
+func Populate(args ...interface{}) Option {
+	_, file, line, _ := runtime.Caller(1)
+	location := fmt.Sprintf("%s:%d", file, line)
+	return newOption(args, "", func(m *module, targets []interface{}, opts provideOptions) error {
+		if err := rejectConditions(m, "goat.Populate", opts); err != nil {
+			return err
+		}
+		in := make([]reflect.Type, len(targets))
+		for i, target := range targets {
+			t := reflect.TypeOf(target)
+			if t == nil || t.Kind() != reflect.Pointer || reflect.ValueOf(target).IsNil() {
+				return fmt.Errorf("%s > goat.Populate expects non-nil pointers, got %v", m.scope.path(), t)
+			}
+			in[i] = t.Elem()
+		}
+		fn := reflect.MakeFunc(reflect.FuncOf(in, nil, false), func(args []reflect.Value) []reflect.Value {
+			for i, arg := range args {
+				reflect.ValueOf(targets[i]).Elem().Set(arg)
+			}
+			return nil
+		})
+		return m.goat.container.addInvoke(m.scope, fn, "goat.Populate", location, opts)
+	})
+}
+
+// Decorate registers functions that take an already provided component and return its replacement of the same type.
+// Decorators of the same type apply in registration order, each receiving the result of the previous one.
+func Decorate(args ...interface{}) Option {
+	return newOption(args, "decorator", func(m *module, decorators []interface{}, opts provideOptions) error {
+		if err := rejectConditions(m, "goat.Decorate", opts); err != nil {
+			return err
+		}
+		for _, decorator := range decorators {
+			if err := m.goat.container.decorate(m.scope, decorator, opts); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func rejectConditions(m *module, option string, opts provideOptions) error {
+	if len(opts.conditions) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s > %s does not support %s, conditions apply to goat.Provide, goat.Override and goat.Replace only",
+		m.scope.path(), option, opts.conditions[0].name)
+}
+
+// newOption splits args into values and ProvideOption arguments and returns an Option passing them to apply,
+// unless a ProvideOption is invalid. If kind is not empty, every value must be a function and kind names it in the panic.
+func newOption(args []interface{}, kind string, apply func(m *module, values []interface{}, opts provideOptions) error) Option {
+	values, opts := splitProvideOptions(args)
+	if kind != "" {
+		for _, value := range values {
+			fnType := reflect.TypeOf(value)
+			if fnType == nil || fnType.Kind() != reflect.Func {
+				panic(kind + " must be a function")
+			}
+		}
+	}
+
+	return optionFunc(func(m *module) {
+		if opts.err != nil {
+			m.fail(fmt.Errorf("%s > %w", m.scope.path(), opts.err))
+			return
+		}
+		if err := apply(m, values, opts); err != nil {
+			m.fail(err)
+		}
+	})
+}
+
+// Configuration provides each configuration struct decoded from the Environment on Start.
+// A value is provided as its struct type and a pointer as its pointer type.
 func Configuration(configurations ...interface{}) Option {
-	return nil
+	_, file, line, _ := runtime.Caller(1)
+	location := fmt.Sprintf("%s:%d", file, line)
+
+	return optionFunc(func(m *module) {
+		for _, configuration := range configurations {
+			t := reflect.TypeOf(configuration)
+			if t == nil {
+				continue
+			}
+			elem := t
+			if t.Kind() == reflect.Pointer {
+				elem = t.Elem()
+			}
+			fnType := reflect.FuncOf(nil, []reflect.Type{t, errorType}, false)
+			fn := reflect.MakeFunc(fnType, func([]reflect.Value) []reflect.Value {
+				instance := reflect.New(elem)
+				if _, err := m.goat.environment.Configuration(instance.Interface()); err != nil {
+					err = &exitError{err: fmt.Errorf("could not decode configuration %v: %w", elem, err), code: ExitCodeConfigInvalid}
+					return []reflect.Value{reflect.Zero(t), reflect.ValueOf(&err).Elem()}
+				}
+				if t.Kind() != reflect.Pointer {
+					instance = instance.Elem()
+				}
+				return []reflect.Value{instance, reflect.Zero(errorType)}
+			})
+			name := fmt.Sprintf("goat.Configuration(%v)", t)
+			p, err := newFuncProvider(fn, provideOptions{}, name, location)
+			if err != nil {
+				m.fail(fmt.Errorf("%s > %w", m.scope.path(), err))
+				continue
+			}
+			if err := m.goat.container.register(m.scope, p); err != nil {
+				m.fail(err)
+			}
+		}
+	})
+}
+
+func (g *Goat) fail(err error) {
+	if g.err == nil {
+		g.err = err
+	}
 }
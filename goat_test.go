@@ -0,0 +1,228 @@
+package goat
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func Test_Goat_Invoke(t *testing.T) {
+	t.Run("should run invoke with injected parameters after every component is built", func(t *testing.T) {
+		order := make([]string, 0)
+		g := New(
+			Invoke(func(bar *testBar) {
+				order = append(order, "invoke:"+bar.foo.name)
+			}),
+			Provide(func() *testFoo {
+				order = append(order, "foo")
+				return newTestFoo()
+			}, func(foo *testFoo) *testBar {
+				order = append(order, "bar")
+				return newTestBar(foo)
+			}),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, []string{"foo", "bar", "invoke:foo"}, order)
+	})
+
+	t.Run("should run invokes in registration order", func(t *testing.T) {
+		order := make([]int, 0)
+		g := New(
+			Invoke(func() { order = append(order, 1) }, func() { order = append(order, 2) }),
+			Invoke(func() { order = append(order, 3) }),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, []int{1, 2, 3}, order)
+	})
+
+	t.Run("should abort start if invoke returns error", func(t *testing.T) {
+		called := false
+		g := New(
+			Invoke(func() error { return errors.New("warm up failed") }),
+			Invoke(func() { called = true }),
+		)
+		err := g.Start(context.Background())
+		assert.ErrorContains(t, err, "warm up failed")
+		assert.Equal(t, err, g.err)
+		assert.False(t, called)
+	})
+
+	t.Run("should abort start if invoke dependency is missing", func(t *testing.T) {
+		g := New(Invoke(func(foo *testFoo) {}))
+		assert.ErrorContains(t, g.Start(context.Background()), "missing dependency *goat.testFoo")
+	})
+
+	t.Run("should not run invokes again if the app is started twice", func(t *testing.T) {
+		calls := 0
+		g := New(Invoke(func() { calls++ }))
+		assert.NoError(t, g.Start(context.Background()))
+		assert.ErrorContains(t, g.Start(context.Background()), "app is already started")
+		assert.NoError(t, g.Stop(context.Background()))
+		assert.ErrorContains(t, g.Start(context.Background()), "app is stopped and cannot be started again")
+		assert.NoError(t, g.Stop(context.Background()))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("should panic if invoke target is not a function", func(t *testing.T) {
+		assert.Panics(t, func() {
+			Invoke(1)
+		})
+	})
+}
+
+func Test_Goat_Settings(t *testing.T) {
+	t.Run("should activate given profiles and properties", func(t *testing.T) {
+		g := New(
+			Profiles("test"),
+			ResourcePath("environment"),
+			Properties(map[string]string{"test.value.string": "override"}),
+		)
+		assert.Equal(t, []string{"default", "test"}, g.profile.Get())
+		assert.Equal(t, 100, g.environment.GetPropertyInt("test.value.int", 0))
+		assert.Equal(t, "override", g.environment.GetProperty("test.value.string", ""))
+		assert.False(t, g.environment.ContainsProperty("app.name"))
+	})
+
+	t.Run("should fail start if configuration cannot be decoded", func(t *testing.T) {
+		type cfg struct {
+			Missing string `properties:"not.exist"`
+		}
+		g := New(Configuration(cfg{}))
+		assert.ErrorContains(t, g.Start(context.Background()), "could not decode configuration goat.cfg")
+	})
+}
+
+func Test_Goat_Populate(t *testing.T) {
+	t.Run("should populate targets with built components", func(t *testing.T) {
+		var foo *testFoo
+		var db *testDB
+		g := New(
+			Provide(newTestFoo),
+			Provide(func() *testDB { return &testDB{dsn: "primary"} }, Name("primary")),
+			Populate(&foo),
+			Populate(&db, ParamTags(`name:"primary"`)),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, "foo", foo.name)
+		assert.Equal(t, "primary", db.dsn)
+	})
+
+	t.Run("should fail start if target is not a pointer", func(t *testing.T) {
+		g := New(Populate(testFoo{}))
+		assert.ErrorContains(t, g.Start(context.Background()), "goat.Populate expects non-nil pointers")
+	})
+}
+
+func Test_Goat_Run(t *testing.T) {
+	signal := func(n int) func() <-chan os.Signal {
+		return func() <-chan os.Signal {
+			ch := make(chan os.Signal, n)
+			for i := 0; i < n; i++ {
+				ch <- syscall.SIGTERM
+			}
+			return ch
+		}
+	}
+
+	t.Run("should exit with 0 after a clean stop", func(t *testing.T) {
+		g := New(Logger(log.New(io.Discard, "", 0)))
+		assert.Equal(t, 0, g.run(signal(1)))
+	})
+
+	t.Run("should exit with 1 if start fails", func(t *testing.T) {
+		g := New(Logger(log.New(io.Discard, "", 0)), Invoke(func() error { return errors.New("boom") }))
+		assert.Equal(t, 1, g.run(signal(1)))
+	})
+
+	t.Run("should dump goroutines to the configured file if stop times out", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		path := filepath.Join(t.TempDir(), "dump.txt")
+		g := New(
+			Logger(log.New(io.Discard, "", 0)),
+			StopTimeout(20*time.Millisecond),
+			GoroutineDumpFile(path),
+			Invoke(func(lc Lifecycle) {
+				lc.Append(Hook{OnStop: func(ctx context.Context) error {
+					<-release
+					return nil
+				}})
+			}),
+		)
+		assert.Equal(t, ExitCodeShutdownTimeout, g.run(signal(1)))
+
+		dump, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Contains(t, string(dump), "goroutine dump: stop timed out")
+		assert.Contains(t, string(dump), "Test_Goat_Run")
+	})
+
+	t.Run("should dump goroutines if a cleanup outlasts the stop timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		path := filepath.Join(t.TempDir(), "dump.txt")
+		g := New(
+			Logger(log.New(io.Discard, "", 0)),
+			StopTimeout(20*time.Millisecond),
+			GoroutineDumpFile(path),
+			Provide(func() (*testFoo, func()) {
+				return newTestFoo(), func() { <-release }
+			}),
+		)
+		assert.Equal(t, ExitCodeShutdownTimeout, g.run(signal(1)))
+
+		dump, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Contains(t, string(dump), "goroutine dump: stop timed out")
+	})
+
+	t.Run("should not dump goroutines if only a hook stop timeout passed", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		path := filepath.Join(t.TempDir(), "dump.txt")
+		g := New(
+			Logger(log.New(io.Discard, "", 0)),
+			GoroutineDumpFile(path),
+			Invoke(func(lc Lifecycle) {
+				lc.Append(Hook{
+					OnStop: func(ctx context.Context) error {
+						<-release
+						return nil
+					},
+					StopTimeout: 5 * time.Millisecond,
+				})
+				lc.Append(Hook{OnStop: func(ctx context.Context) error { return context.DeadlineExceeded }})
+			}),
+		)
+		assert.Equal(t, ExitCodeFailure, g.run(signal(1)))
+		assert.NoFileExists(t, path)
+	})
+
+	t.Run("should dump goroutines if a second signal arrives while stopping", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		path := filepath.Join(t.TempDir(), "dump.txt")
+		g := New(
+			Logger(log.New(io.Discard, "", 0)),
+			Properties(map[string]string{"goat.lifecycle.dump-file": path}),
+			Invoke(func(lc Lifecycle) {
+				lc.Append(Hook{OnStop: func(ctx context.Context) error {
+					<-release
+					return nil
+				}})
+			}),
+		)
+		assert.Equal(t, ExitCodeShutdownTimeout, g.run(signal(2)))
+
+		dump, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Contains(t, string(dump), "goroutine dump: received terminated while stopping")
+	})
+}
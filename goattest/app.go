@@ -0,0 +1,67 @@
+package goattest
+
+import (
+	"context"
+	"github.com/PCloud63514/goat"
+	"testing"
+	"time"
+)
+
+const (
+	defaultLeakTimeout = time.Second
+)
+
+// App is a Goat app driven by a test instead of Goat.Run.
+type App struct {
+	*goat.Goat
+	tb       testing.TB
+	baseline map[string]bool
+	started  bool
+	stopped  bool
+}
+
+// New builds an app with goat.New that only activates the default profile and reads no resource files.
+// Options such as goat.Profiles and goat.Properties given here take precedence.
+// An app that was started but not stopped is stopped when the test finishes.
+func New(tb testing.TB, opts ...goat.Option) *App {
+	tb.Helper()
+	defaults := []goat.Option{
+		goat.Profiles(),
+		goat.ResourcePath(tb.TempDir()),
+	}
+	app := &App{
+		Goat: goat.New(append(defaults, opts...)...),
+		tb:   tb,
+	}
+	tb.Cleanup(func() {
+		if app.started && !app.stopped {
+			app.RequireStop()
+		}
+	})
+	return app
+}
+
+// RequireStart starts the app and fails the test immediately if Start returns an error.
+func (app *App) RequireStart() *App {
+	app.tb.Helper()
+	app.baseline = goroutines()
+	app.started = true
+	if err := app.Start(context.Background()); err != nil {
+		app.tb.Fatalf("goattest: start failed: %v", err)
+	}
+	return app
+}
+
+// RequireStop stops the app and fails the test if Stop returns an error
+// or if goroutines started since RequireStart are still running.
+// Leak detection is unreliable for tests running in parallel.
+func (app *App) RequireStop() {
+	app.tb.Helper()
+	app.stopped = true
+	if err := app.Stop(context.Background()); err != nil {
+		app.tb.Errorf("goattest: stop failed: %v", err)
+	}
+	if leaked := leakedGoroutines(app.baseline, defaultLeakTimeout); len(leaked) > 0 {
+		app.tb.Errorf("goattest: %d goroutines still running after stop:\n\n%s", len(leaked), joinStacks(leaked))
+	}
+}
@@ -0,0 +1,98 @@
+package goattest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/PCloud63514/goat"
+	"github.com/stretchr/testify/assert"
+	"runtime"
+	"testing"
+)
+
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+func run(t *testing.T, fn func(tb testing.TB)) *recorder {
+	r := &recorder{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(r)
+	}()
+	<-done
+	return r
+}
+
+type server struct {
+	Host string
+	Port int
+}
+
+type serverConfiguration struct {
+	Host string `properties:"server.host"`
+	Port int    `properties:"server.port"`
+}
+
+func Test_App(t *testing.T) {
+	t.Run("should start app with injected properties and populate components", func(t *testing.T) {
+		var s *server
+		app := New(t,
+			goat.Properties(map[string]string{"server.host": "test-host", "server.port": "9090"}),
+			goat.Configuration(serverConfiguration{}),
+			goat.Provide(func(cfg serverConfiguration) *server {
+				return &server{Host: cfg.Host, Port: cfg.Port}
+			}),
+			goat.Populate(&s),
+		).RequireStart()
+		defer app.RequireStop()
+
+		assert.Equal(t, "test-host", s.Host)
+		assert.Equal(t, 9090, s.Port)
+	})
+
+	t.Run("should fail test if start returns error", func(t *testing.T) {
+		r := run(t, func(tb testing.TB) {
+			New(tb, goat.Invoke(func() error { return errors.New("boom") })).RequireStart()
+		})
+		assert.Len(t, r.errors, 1)
+		assert.Contains(t, r.errors[0], "boom")
+	})
+
+	t.Run("should fail test if goroutines are still running after stop", func(t *testing.T) {
+		release := make(chan struct{})
+		r := run(t, func(tb testing.TB) {
+			app := New(tb, goat.Invoke(func() {
+				go func() { <-release }()
+			})).RequireStart()
+			app.RequireStop()
+		})
+		close(release)
+		assert.Len(t, r.errors, 1)
+		assert.Contains(t, r.errors[0], "1 goroutines still running after stop")
+	})
+
+	t.Run("should not report goroutines released by cleanup", func(t *testing.T) {
+		r := run(t, func(tb testing.TB) {
+			app := New(tb, goat.Provide(func() (*server, func()) {
+				release := make(chan struct{})
+				go func() { <-release }()
+				return &server{}, func() { close(release) }
+			})).RequireStart()
+			app.RequireStop()
+		})
+		assert.Empty(t, r.errors)
+	})
+}
@@ -0,0 +1,57 @@
+package goattest
+
+import (
+	"runtime"
+	"strings"
+	"time"
+)
+
+func goroutines() map[string]bool {
+	ids := make(map[string]bool)
+	for id := range stacks() {
+		ids[id] = true
+	}
+	return ids
+}
+
+func stacks() map[string]string {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+
+	result := make(map[string]string)
+	for _, stack := range strings.Split(string(buf), "\n\n") {
+		fields := strings.Fields(stack)
+		if len(fields) < 2 || fields[0] != "goroutine" {
+			continue
+		}
+		result[fields[1]] = stack
+	}
+	return result
+}
+
+func leakedGoroutines(baseline map[string]bool, timeout time.Duration) []string {
+	deadline := time.Now().Add(timeout)
+	for {
+		leaked := make([]string, 0)
+		for id, stack := range stacks() {
+			if !baseline[id] {
+				leaked = append(leaked, stack)
+			}
+		}
+		if len(leaked) == 0 || time.Now().After(deadline) {
+			return leaked
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func joinStacks(stacks []string) string {
+	return strings.Join(stacks, "\n\n")
+}
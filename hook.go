@@ -1,11 +1,21 @@
 package goat
 
+import (
+	"context"
+	"time"
+)
+
 type HookType int
 
-type HookFunc func()
+type HookFunc func(ctx context.Context) error
 
+// Deprecated: nothing runs these. Use Hook with Lifecycle.Append instead.
 type HookOnStartFunc func()
+
+// Deprecated: nothing runs these. Use Hook with Lifecycle.Append instead.
 type HookOnStopFunc func()
+
+// Deprecated: nothing runs these. Use Hook with Lifecycle.Append instead.
 type HookOnShutdownFunc func()
 
 const (
@@ -14,8 +24,59 @@ const (
 	hookType_Shutdown
 )
 
+func (t HookType) String() string {
+	switch t {
+	case hookType_Start:
+		return "OnStart"
+	case hookType_Stop:
+		return "OnStop"
+	case hookType_Shutdown:
+		return "OnShutdown"
+	}
+	return "Unknown"
+}
+
+// Hook is registered through Lifecycle.Append.
+// OnStart runs on Goat.Start after the hooks of the components the registering function depends on, and OnStop
+// runs on Goat.Stop in reverse order for the hooks whose OnStart succeeded. OnShutdown runs for the same hooks
+// in reverse order after every OnStop finished, even if one of them failed, and also for the hooks stopped
+// when Goat.Start failed. See HookConcurrency for running hooks at the same time.
+// StartTimeout bounds OnStart and StopTimeout bounds OnStop and OnShutdown, on top of the app timeouts.
+// A hook whose OnStart timed out or was cancelled is stopped like a started one, as OnStart may still be running.
+// Components whose hooks are discovered take these timeouts from StartTimeout() and StopTimeout() methods
+// returning a time.Duration.
+//
+// Phase orders hooks before their dependencies do: Goat.Start runs the phases in ascending order and Goat.Stop in
+// descending order, each phase finishing before the next one begins. Components whose hooks are discovered
+// take their phase from a Phase() int method. The default phase is 0. Dependencies only order hooks of the same
+// phase: a hook depending on a hook of a later phase still starts in its own phase.
 type Hook struct {
-	OnStart    func() error
-	OnStop     func() error
-	OnShutdown func() error
+	OnStart      HookFunc
+	OnStop       HookFunc
+	OnShutdown   HookFunc
+	StartTimeout time.Duration
+	StopTimeout  time.Duration
+	Phase        int
+}
+
+// Lifecycle is injected into constructors and invoke functions that register hooks explicitly.
+type Lifecycle interface {
+	Append(hook Hook)
+}
+
+type ownedLifecycle struct {
+	container *container
+	owner     *owner
+}
+
+func (l *ownedLifecycle) Append(h Hook) {
+	l.container.addHook(l.owner, &hook{
+		name:         l.owner.name,
+		onStart:      h.OnStart,
+		onStop:       h.OnStop,
+		onShutdown:   h.OnShutdown,
+		startTimeout: h.StartTimeout,
+		stopTimeout:  h.StopTimeout,
+		phase:        h.Phase,
+	})
 }
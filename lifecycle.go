@@ -1 +1,383 @@
 package goat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+var (
+	hookFuncType  = reflect.TypeOf((func(ctx context.Context) error)(nil))
+	lifecycleType = reflect.TypeOf((*Lifecycle)(nil)).Elem()
+)
+
+type starter interface {
+	Start(ctx context.Context) error
+}
+
+type stopper interface {
+	Stop(ctx context.Context) error
+}
+
+type phased interface {
+	Phase() int
+}
+
+type startTimed interface {
+	StartTimeout() time.Duration
+}
+
+type stopTimed interface {
+	StopTimeout() time.Duration
+}
+
+type hook struct {
+	name         string
+	onStart      HookFunc
+	onStop       HookFunc
+	onShutdown   HookFunc
+	startTimeout time.Duration
+	stopTimeout  time.Duration
+	phase        int
+	deps         []*hook
+}
+
+// run calls the hook function of type t and gives up on it once ctx or the hook's own timeout is done,
+// so a hook that ignores its context cannot block the lifecycle. abandoned reports that the function
+// may still be running.
+func (h *hook) run(ctx context.Context, t HookType) (abandoned bool, err error) {
+	fn, timeout := h.onStart, h.startTimeout
+	switch t {
+	case hookType_Stop:
+		fn, timeout = h.onStop, h.stopTimeout
+	case hookType_Shutdown:
+		fn, timeout = h.onShutdown, h.stopTimeout
+	}
+	if fn == nil {
+		return false, nil
+	}
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	if err := ctx.Err(); err != nil {
+		return false, fmt.Errorf("%s %v was not run: %w", h.name, t, err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return false, fmt.Errorf("%s %v failed: %w", h.name, t, err)
+		}
+		return false, nil
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return true, fmt.Errorf("%s %v timed out: %w", h.name, t, ctx.Err())
+		}
+		return true, fmt.Errorf("%s %v was cancelled: %w", h.name, t, ctx.Err())
+	}
+}
+
+// lifecycle runs the hooks phase by phase. startTimeout and stopTimeout bound the hooks of a whole Start and Stop,
+// zero meaning no limit. Within a phase up to concurrency hooks run at once, each one only after the hooks it depends on.
+type lifecycle struct {
+	hooks        []*hook
+	started      map[*hook]bool
+	shutdown     map[*hook]bool
+	startTimeout time.Duration
+	stopTimeout  time.Duration
+	concurrency  int
+	timeline     *timeline
+}
+
+func newLifecycle(timeline *timeline) *lifecycle {
+	return &lifecycle{
+		hooks:       make([]*hook, 0),
+		started:     make(map[*hook]bool),
+		shutdown:    make(map[*hook]bool),
+		concurrency: 1,
+		timeline:    timeline,
+	}
+}
+
+func (l *lifecycle) append(hooks ...*hook) {
+	l.hooks = append(l.hooks, hooks...)
+}
+
+// start runs OnStart of the hooks not started yet, lowest phase first. If one fails, no other hook is started and
+// the hooks that already started, or whose OnStart was given up on while still running, are stopped in reverse order.
+// The failure is returned together with the rollback errors.
+func (l *lifecycle) start(ctx context.Context) error {
+	startCtx, cancel := withTimeout(ctx, l.startTimeout)
+	defer cancel()
+	pending := make([]*hook, 0, len(l.hooks))
+	for _, h := range l.hooks {
+		if !l.started[h] {
+			pending = append(pending, h)
+		}
+	}
+	var errs []error
+	for _, hooks := range phases(pending, false) {
+		var started []*hook
+		started, errs = l.runGraph(startCtx, hooks, hookType_Start)
+		for i, err := range errs {
+			errs[i] = withExitCode(err, ExitCodeDependencyUnavailable)
+		}
+		for _, h := range started {
+			l.started[h] = true
+			l.shutdown[h] = true
+		}
+		if len(errs) > 0 {
+			break
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	err := errors.Join(errs...)
+	if rollbackErr := l.rollback(context.WithoutCancel(ctx)); rollbackErr != nil {
+		return errors.Join(err, fmt.Errorf("rollback failed: %w", rollbackErr))
+	}
+	return err
+}
+
+func (l *lifecycle) rollback(ctx context.Context) error {
+	ctx, cancel := withTimeout(ctx, l.stopTimeout)
+	defer cancel()
+	return l.stopStarted(ctx)
+}
+
+func (l *lifecycle) stopStarted(ctx context.Context) error {
+	started := make([]*hook, 0, len(l.started))
+	for i := len(l.hooks) - 1; i >= 0; i-- {
+		if l.started[l.hooks[i]] {
+			started = append(started, l.hooks[i])
+		}
+	}
+	errs := make([]error, 0)
+	for _, hooks := range phases(started, true) {
+		_, stopErrs := l.runGraph(ctx, hooks, hookType_Stop)
+		errs = append(errs, stopErrs...)
+	}
+	clear(l.started)
+	return errors.Join(errs...)
+}
+
+func (l *lifecycle) stop(ctx context.Context) error {
+	ctx, cancel := withTimeout(ctx, l.stopTimeout)
+	defer cancel()
+	errs := make([]error, 0)
+	if err := l.stopStarted(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	hooks := make([]*hook, 0, len(l.shutdown))
+	for i := len(l.hooks) - 1; i >= 0; i-- {
+		if l.shutdown[l.hooks[i]] {
+			hooks = append(hooks, l.hooks[i])
+		}
+	}
+	for _, phase := range phases(hooks, true) {
+		_, shutdownErrs := l.runGraph(ctx, phase, hookType_Shutdown)
+		errs = append(errs, shutdownErrs...)
+	}
+	clear(l.shutdown)
+	l.hooks = l.hooks[:0]
+	return errors.Join(errs...)
+}
+
+// phases splits hooks by phase, ascending or descending, keeping their order within a phase.
+func phases(hooks []*hook, descending bool) [][]*hook {
+	byPhase := make(map[int][]*hook)
+	numbers := make([]int, 0)
+	for _, h := range hooks {
+		if _, ok := byPhase[h.phase]; !ok {
+			numbers = append(numbers, h.phase)
+		}
+		byPhase[h.phase] = append(byPhase[h.phase], h)
+	}
+	sort.Ints(numbers)
+	result := make([][]*hook, len(numbers))
+	for i, n := range numbers {
+		if descending {
+			i = len(numbers) - 1 - i
+		}
+		result[i] = byPhase[n]
+	}
+	return result
+}
+
+// runGraph runs the hook functions of type t, picking the hooks in the given order as soon as their turn comes.
+// On start a hook's turn comes once its dependencies in hooks finished; on stop and shutdown, once its dependents did.
+// OnStart stops scheduling after the first failure. runGraph returns the hooks that succeeded or were given up on
+// while still running, and the failures.
+func (l *lifecycle) runGraph(ctx context.Context, hooks []*hook, t HookType) ([]*hook, []error) {
+	type outcome struct {
+		hook      *hook
+		abandoned bool
+		err       error
+	}
+
+	contains := make(map[*hook]bool, len(hooks))
+	for _, h := range hooks {
+		contains[h] = true
+	}
+	waiting := make(map[*hook]int, len(hooks))
+	next := make(map[*hook][]*hook, len(hooks))
+	for _, h := range hooks {
+		for _, d := range h.deps {
+			if !contains[d] {
+				continue
+			}
+			if t == hookType_Start {
+				waiting[h]++
+				next[d] = append(next[d], h)
+			} else {
+				waiting[d]++
+				next[h] = append(next[h], d)
+			}
+		}
+	}
+
+	succeeded := make([]*hook, 0, len(hooks))
+	errs := make([]error, 0)
+	outcomes := make(chan outcome)
+	pending := append(make([]*hook, 0, len(hooks)), hooks...)
+	running := 0
+	for {
+		aborted := t == hookType_Start && len(errs) > 0
+		for i := 0; !aborted && i < len(pending) && running < l.concurrency; {
+			h := pending[i]
+			if waiting[h] > 0 {
+				i++
+				continue
+			}
+			pending = append(pending[:i], pending[i+1:]...)
+			running++
+			go func() {
+				begin := time.Now()
+				abandoned, err := h.run(ctx, t)
+				if t == hookType_Start && h.onStart != nil {
+					l.timeline.record(t.String(), h.name, begin)
+				}
+				outcomes <- outcome{hook: h, abandoned: abandoned, err: err}
+			}()
+		}
+		if running == 0 {
+			return succeeded, errs
+		}
+
+		o := <-outcomes
+		running--
+		if o.err == nil || o.abandoned {
+			succeeded = append(succeeded, o.hook)
+		}
+		if o.err != nil {
+			errs = append(errs, o.err)
+			if t == hookType_Start {
+				continue
+			}
+		}
+		for _, h := range next[o.hook] {
+			waiting[h]--
+		}
+	}
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// discoverHooks finds the components built by p that implement Start(ctx) error / Stop(ctx) error
+// or carry OnStart / OnStop func(ctx) error fields.
+func discoverHooks(p *provider) []*hook {
+	type position struct{ index, field int }
+	seen := make(map[position]bool)
+	hooks := make([]*hook, 0)
+	for _, r := range p.results {
+		pos := position{r.index, r.field}
+		if seen[pos] {
+			continue
+		}
+		seen[pos] = true
+
+		v := p.values[r.index]
+		if r.field >= 0 {
+			v = v.Field(r.field)
+		}
+		if h := newComponentHook(v); h != nil {
+			h.name = fmt.Sprintf("%s > %v (%s)", p.scope.path(), v.Type(), p.name)
+			hooks = append(hooks, h)
+		}
+	}
+	return hooks
+}
+
+func newComponentHook(v reflect.Value) *hook {
+	if v.Type() == lifecycleType {
+		return nil
+	}
+	if !v.IsValid() || isNil(v) {
+		return nil
+	}
+	h := &hook{}
+	if s, ok := v.Interface().(starter); ok {
+		h.onStart = s.Start
+	} else {
+		h.onStart = hookField(v, "OnStart")
+	}
+	if s, ok := v.Interface().(stopper); ok {
+		h.onStop = s.Stop
+	} else {
+		h.onStop = hookField(v, "OnStop")
+	}
+	if h.onStart == nil && h.onStop == nil {
+		return nil
+	}
+	if p, ok := v.Interface().(phased); ok {
+		h.phase = p.Phase()
+	}
+	if t, ok := v.Interface().(startTimed); ok {
+		h.startTimeout = t.StartTimeout()
+	}
+	if t, ok := v.Interface().(stopTimed); ok {
+		h.stopTimeout = t.StopTimeout()
+	}
+	return h
+}
+
+func hookField(v reflect.Value, name string) HookFunc {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	f, ok := v.Type().FieldByName(name)
+	if !ok || !f.IsExported() || f.Type.Kind() != reflect.Func || !f.Type.ConvertibleTo(hookFuncType) {
+		return nil
+	}
+	fv := v.FieldByIndex(f.Index)
+	if fv.IsNil() {
+		return nil
+	}
+	return fv.Convert(hookFuncType).Interface().(func(ctx context.Context) error)
+}
+
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,584 @@
+package goat
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testRecorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *testRecorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *testRecorder) record(event string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		r.add(event)
+		return nil
+	}
+}
+
+type testHookFields struct {
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+type testHookService struct {
+	name     string
+	recorder *testRecorder
+}
+
+func (s *testHookService) Start(ctx context.Context) error {
+	s.recorder.add(s.name + " start")
+	return nil
+}
+
+func (s *testHookService) Stop(ctx context.Context) error {
+	s.recorder.add(s.name + " stop")
+	return nil
+}
+
+func Test_Goat_ComponentHooks(t *testing.T) {
+	t.Run("should start components in dependency order and stop them in reverse", func(t *testing.T) {
+		r := &testRecorder{}
+		type foo struct{ testHookFields }
+		type bar struct {
+			testHookFields
+			foo *foo
+		}
+		g := New(
+			Provide(func(f *foo) *bar {
+				return &bar{foo: f, testHookFields: testHookFields{OnStart: r.record("bar start"), OnStop: r.record("bar stop")}}
+			}),
+			Provide(func() *foo {
+				return &foo{testHookFields: testHookFields{OnStop: r.record("foo stop")}}
+			}),
+			Provide(func(b *bar) *testHookService {
+				return &testHookService{name: "service", recorder: r}
+			}),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, []string{"bar start", "service start"}, r.events)
+		assert.NoError(t, g.Stop(context.Background()))
+		assert.Equal(t, []string{"bar start", "service start", "service stop", "bar stop", "foo stop"}, r.events)
+	})
+
+	t.Run("should discover hook fields declared as HookFunc", func(t *testing.T) {
+		r := &testRecorder{}
+		type server struct {
+			OnStart HookFunc
+			OnStop  HookFunc
+		}
+		g := New(Provide(func() *server {
+			return &server{OnStart: r.record("server start"), OnStop: r.record("server stop")}
+		}))
+		assert.NoError(t, g.Start(context.Background()))
+		assert.NoError(t, g.Stop(context.Background()))
+		assert.Equal(t, []string{"server start", "server stop"}, r.events)
+	})
+
+	t.Run("should register a component exposed under several types once", func(t *testing.T) {
+		r := &testRecorder{}
+		g := New(Provide(func() *testHookService {
+			return &testHookService{name: "service", recorder: r}
+		}, As(new(starter))))
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, []string{"service start"}, r.events)
+	})
+
+	t.Run("should discover hooks on result struct fields", func(t *testing.T) {
+		r := &testRecorder{}
+		type results struct {
+			Out
+			A *testHookService `name:"a"`
+			B *testHookService `name:"b"`
+		}
+		g := New(Provide(func() results {
+			return results{A: &testHookService{name: "a", recorder: r}, B: &testHookService{name: "b", recorder: r}}
+		}))
+		assert.NoError(t, g.Start(context.Background()))
+		assert.NoError(t, g.Stop(context.Background()))
+		assert.Equal(t, []string{"a start", "b start", "b stop", "a stop"}, r.events)
+	})
+
+	t.Run("should fail start with the hook that failed", func(t *testing.T) {
+		g := New(Provide(func() *testHookFields {
+			return &testHookFields{OnStart: func(ctx context.Context) error { return errors.New("listen failed") }}
+		}))
+		err := g.Start(context.Background())
+		assert.ErrorContains(t, err, "app > *goat.testHookFields")
+		assert.ErrorContains(t, err, "OnStart failed: listen failed")
+	})
+
+	t.Run("should keep stopping after a stop hook fails", func(t *testing.T) {
+		r := &testRecorder{}
+		g := New(
+			Provide(func() *testHookService { return &testHookService{name: "service", recorder: r} }),
+			Provide(func(s *testHookService) *testHookFields {
+				return &testHookFields{OnStop: func(ctx context.Context) error { return errors.New("close failed") }}
+			}),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.ErrorContains(t, g.Stop(context.Background()), "OnStop failed: close failed")
+		assert.Equal(t, []string{"service start", "service stop"}, r.events)
+	})
+}
+
+func Test_Goat_Lifecycle(t *testing.T) {
+	t.Run("should run appended hooks with component hooks in build order", func(t *testing.T) {
+		r := &testRecorder{}
+		g := New(
+			Provide(func(lc Lifecycle) *testFoo {
+				lc.Append(Hook{OnStart: r.record("foo start"), OnStop: r.record("foo stop"), OnShutdown: r.record("foo shutdown")})
+				return newTestFoo()
+			}),
+			Provide(func(foo *testFoo) *testHookService {
+				return &testHookService{name: "service", recorder: r}
+			}),
+			Invoke(func(lc Lifecycle) {
+				lc.Append(Hook{OnStart: r.record("invoke start"), OnStop: r.record("invoke stop")})
+			}),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, []string{"foo start", "service start", "invoke start"}, r.events)
+		r.events = r.events[:0]
+		assert.NoError(t, g.Stop(context.Background()))
+		assert.Equal(t, []string{"invoke stop", "service stop", "foo stop", "foo shutdown"}, r.events)
+	})
+
+	t.Run("should run shutdown hooks after every stop even if a stop failed", func(t *testing.T) {
+		r := &testRecorder{}
+		g := New(
+			Invoke(func(lc Lifecycle) {
+				lc.Append(Hook{OnStop: r.record("a stop"), OnShutdown: r.record("a shutdown")})
+				lc.Append(Hook{
+					OnStop:     func(ctx context.Context) error { return errors.New("stop failed") },
+					OnShutdown: r.record("b shutdown"),
+				})
+				lc.Append(Hook{OnStop: r.record("c stop")})
+			}),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		err := g.Stop(context.Background())
+		assert.ErrorContains(t, err, "OnStop failed: stop failed")
+		assert.Equal(t, []string{"c stop", "a stop", "b shutdown", "a shutdown"}, r.events)
+	})
+
+	t.Run("should run shutdown hooks only for hooks that started", func(t *testing.T) {
+		r := &testRecorder{}
+		g := New(
+			Invoke(func(lc Lifecycle) {
+				lc.Append(Hook{OnStart: r.record("a start"), OnShutdown: r.record("a shutdown")})
+				lc.Append(Hook{
+					OnStart:    func(ctx context.Context) error { return errors.New("boom") },
+					OnShutdown: r.record("b shutdown"),
+				})
+				lc.Append(Hook{OnShutdown: r.record("c shutdown")})
+			}),
+		)
+		assert.ErrorContains(t, g.Start(context.Background()), "boom")
+		assert.NoError(t, g.Stop(context.Background()))
+		assert.Equal(t, []string{"a start", "a shutdown"}, r.events)
+	})
+
+	t.Run("should name the owner of the failed hook", func(t *testing.T) {
+		g := New(Module("server",
+			Provide(func(lc Lifecycle) *testFoo {
+				lc.Append(Hook{OnStart: func(ctx context.Context) error { return errors.New("bind failed") }})
+				return newTestFoo()
+			}),
+		))
+		err := g.Start(context.Background())
+		assert.ErrorContains(t, err, "app > server > github.com/PCloud63514/goat.Test_Goat_Lifecycle")
+		assert.ErrorContains(t, err, "OnStart failed: bind failed")
+	})
+
+	t.Run("should inject lifecycle into parameter struct", func(t *testing.T) {
+		r := &testRecorder{}
+		type params struct {
+			In
+			Lifecycle Lifecycle
+		}
+		g := New(Invoke(func(p params) {
+			p.Lifecycle.Append(Hook{OnStart: r.record("start")})
+		}))
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, []string{"start"}, r.events)
+	})
+}
+
+func Test_Goat_Rollback(t *testing.T) {
+	t.Run("should stop started hooks in reverse order if a start hook fails", func(t *testing.T) {
+		r := &testRecorder{}
+		g := New(Invoke(func(lc Lifecycle) {
+			lc.Append(Hook{OnStart: r.record("a start"), OnStop: r.record("a stop")})
+			lc.Append(Hook{OnStart: r.record("b start"), OnStop: r.record("b stop")})
+			lc.Append(Hook{
+				OnStart: func(ctx context.Context) error { return errors.New("c failed") },
+				OnStop:  r.record("c stop"),
+			})
+			lc.Append(Hook{OnStart: r.record("d start"), OnStop: r.record("d stop")})
+		}))
+		err := g.Start(context.Background())
+		assert.ErrorContains(t, err, "c failed")
+		assert.Equal(t, err, g.err)
+		assert.Equal(t, []string{"a start", "b start", "b stop", "a stop"}, r.events)
+
+		assert.NoError(t, g.Stop(context.Background()))
+		assert.Equal(t, []string{"a start", "b start", "b stop", "a stop"}, r.events)
+	})
+
+	t.Run("should combine the start failure with rollback errors", func(t *testing.T) {
+		startErr := errors.New("start failed")
+		stopErr := errors.New("stop failed")
+		r := &testRecorder{}
+		g := New(Invoke(func(lc Lifecycle) {
+			lc.Append(Hook{OnStart: r.record("a start"), OnStop: r.record("a stop")})
+			lc.Append(Hook{OnStop: func(ctx context.Context) error { return stopErr }})
+			lc.Append(Hook{OnStart: func(ctx context.Context) error { return startErr }})
+		}))
+		err := g.Start(context.Background())
+		assert.ErrorIs(t, err, startErr)
+		assert.ErrorIs(t, err, stopErr)
+		assert.ErrorContains(t, err, "rollback failed")
+		assert.Equal(t, []string{"a start", "a stop"}, r.events)
+	})
+
+	t.Run("should roll back with a context that is not cancelled", func(t *testing.T) {
+		var rollbackErr error
+		ctx, cancel := context.WithCancel(context.Background())
+		g := New(Invoke(func(lc Lifecycle) {
+			lc.Append(Hook{OnStop: func(ctx context.Context) error {
+				rollbackErr = ctx.Err()
+				return nil
+			}})
+			lc.Append(Hook{OnStart: func(ctx context.Context) error {
+				cancel()
+				return ctx.Err()
+			}})
+		}))
+		assert.ErrorIs(t, g.Start(ctx), context.Canceled)
+		assert.NoError(t, rollbackErr)
+	})
+}
+
+type testTimedService struct {
+	release chan struct{}
+}
+
+func (s *testTimedService) Start(ctx context.Context) error {
+	<-s.release
+	return nil
+}
+
+func (s *testTimedService) StartTimeout() time.Duration {
+	return 20 * time.Millisecond
+}
+
+func Test_Goat_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	hang := func(ctx context.Context) error {
+		<-release
+		return nil
+	}
+
+	t.Run("should name the start hook that exceeded the app start timeout", func(t *testing.T) {
+		r := &testRecorder{}
+		g := New(
+			StartTimeout(20*time.Millisecond),
+			Invoke(func(lc Lifecycle) {
+				lc.Append(Hook{OnStart: r.record("a start"), OnStop: r.record("a stop")})
+				lc.Append(Hook{OnStart: hang})
+			}),
+		)
+		err := g.Start(context.Background())
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.ErrorContains(t, err, "app > github.com/PCloud63514/goat.Test_Goat_Timeout")
+		assert.ErrorContains(t, err, "OnStart timed out")
+		assert.Equal(t, []string{"a start", "a stop"}, r.events)
+	})
+
+	t.Run("should give up on a stop hook that ignores its context", func(t *testing.T) {
+		r := &testRecorder{}
+		g := New(
+			StopTimeout(20*time.Millisecond),
+			Invoke(func(lc Lifecycle) {
+				lc.Append(Hook{OnShutdown: r.record("a shutdown")})
+				lc.Append(Hook{OnStop: hang})
+			}),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		err := g.Stop(context.Background())
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.ErrorContains(t, err, "OnStop timed out")
+		assert.ErrorContains(t, err, "OnShutdown was not run")
+		assert.Empty(t, r.events)
+	})
+
+	t.Run("should apply the hook timeout to its own hook only", func(t *testing.T) {
+		r := &testRecorder{}
+		g := New(Invoke(func(lc Lifecycle) {
+			lc.Append(Hook{OnStop: r.record("a stop")})
+			lc.Append(Hook{OnStop: hang, StopTimeout: 20 * time.Millisecond})
+		}))
+		assert.NoError(t, g.Start(context.Background()))
+		err := g.Stop(context.Background())
+		assert.ErrorContains(t, err, "OnStop timed out")
+		assert.Equal(t, []string{"a stop"}, r.events)
+	})
+
+	t.Run("should stop a hook whose start timed out", func(t *testing.T) {
+		r := &testRecorder{}
+		g := New(Invoke(func(lc Lifecycle) {
+			lc.Append(Hook{
+				OnStart:      hang,
+				OnStop:       r.record("a stop"),
+				OnShutdown:   r.record("a shutdown"),
+				StartTimeout: 20 * time.Millisecond,
+			})
+		}))
+		assert.ErrorContains(t, g.Start(context.Background()), "OnStart timed out")
+		assert.NoError(t, g.Stop(context.Background()))
+		assert.Equal(t, []string{"a stop", "a shutdown"}, r.events)
+	})
+
+	t.Run("should take the hook timeouts of a component from its methods", func(t *testing.T) {
+		g := New(Provide(func() *testTimedService { return &testTimedService{release: release} }))
+		err := g.Start(context.Background())
+		assert.ErrorContains(t, err, "*goat.testTimedService")
+		assert.ErrorContains(t, err, "OnStart timed out")
+		assert.NoError(t, g.Stop(context.Background()))
+	})
+
+	t.Run("should read the timeouts from properties", func(t *testing.T) {
+		g := New(Properties(map[string]string{
+			"goat.lifecycle.start-timeout": "15s",
+			"goat.lifecycle.stop-timeout":  "30s",
+		}))
+		assert.Equal(t, 15*time.Second, g.lifecycle.startTimeout)
+		assert.Equal(t, 30*time.Second, g.lifecycle.stopTimeout)
+	})
+
+	t.Run("should prefer the timeout options over properties", func(t *testing.T) {
+		g := New(
+			StopTimeout(time.Second),
+			Properties(map[string]string{"goat.lifecycle.stop-timeout": "30s"}),
+		)
+		assert.Equal(t, time.Second, g.lifecycle.stopTimeout)
+	})
+
+	t.Run("should fail start if a timeout property is invalid", func(t *testing.T) {
+		g := New(Properties(map[string]string{"goat.lifecycle.stop-timeout": "soon"}))
+		assert.ErrorContains(t, g.Start(context.Background()), "invalid property goat.lifecycle.stop-timeout")
+	})
+}
+
+func Test_Goat_HookConcurrency(t *testing.T) {
+	t.Run("should run hooks of independent components at the same time", func(t *testing.T) {
+		var running sync.WaitGroup
+		running.Add(2)
+		barrier := func(ctx context.Context) error {
+			running.Done()
+			running.Wait()
+			return nil
+		}
+		g := New(
+			HookConcurrency(2),
+			StartTimeout(time.Second),
+			Provide(func() *testHookFields { return &testHookFields{OnStart: barrier} }),
+			Invoke(func(lc Lifecycle) { lc.Append(Hook{OnStart: barrier}) }),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+	})
+
+	t.Run("should keep dependency order between hooks", func(t *testing.T) {
+		r := &testRecorder{}
+		type foo struct{ testHookFields }
+		type bar struct {
+			testHookFields
+			foo *foo
+		}
+		slow := func(event string) func(ctx context.Context) error {
+			return func(ctx context.Context) error {
+				time.Sleep(20 * time.Millisecond)
+				r.add(event)
+				return nil
+			}
+		}
+		g := New(
+			HookConcurrency(4),
+			Provide(func() *foo {
+				return &foo{testHookFields{OnStart: slow("foo start"), OnStop: r.record("foo stop")}}
+			}),
+			Provide(func(f *foo) *bar {
+				return &bar{foo: f, testHookFields: testHookFields{OnStart: r.record("bar start"), OnStop: slow("bar stop")}}
+			}),
+			Invoke(func(b *bar, lc Lifecycle) {
+				lc.Append(Hook{OnStart: r.record("invoke start")})
+			}),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, []string{"foo start", "bar start", "invoke start"}, r.events)
+		assert.NoError(t, g.Stop(context.Background()))
+		assert.Equal(t, []string{"foo start", "bar start", "invoke start", "bar stop", "foo stop"}, r.events)
+	})
+
+	t.Run("should not run more hooks at once than the limit", func(t *testing.T) {
+		var mu sync.Mutex
+		current, peak := 0, 0
+		track := func(ctx context.Context) error {
+			mu.Lock()
+			current++
+			peak = max(peak, current)
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			current--
+			mu.Unlock()
+			return nil
+		}
+		g := New(
+			HookConcurrency(2),
+			Invoke(func(lc Lifecycle) {
+				for i := 0; i < 6; i++ {
+					lc.Append(Hook{OnStart: track})
+				}
+			}, func(lc Lifecycle) {
+				for i := 0; i < 6; i++ {
+					lc.Append(Hook{OnStart: track})
+				}
+			}),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, 2, peak)
+	})
+
+	t.Run("should not start hooks that depend on a failed hook", func(t *testing.T) {
+		r := &testRecorder{}
+		type foo struct{ testHookFields }
+		type bar struct {
+			testHookFields
+			foo *foo
+		}
+		g := New(
+			HookConcurrency(4),
+			Provide(func() *foo {
+				return &foo{testHookFields{OnStart: func(ctx context.Context) error { return errors.New("foo failed") }}}
+			}),
+			Provide(func(f *foo) *bar {
+				return &bar{foo: f, testHookFields: testHookFields{OnStart: r.record("bar start")}}
+			}),
+		)
+		assert.ErrorContains(t, g.Start(context.Background()), "foo failed")
+		assert.Empty(t, r.events)
+	})
+
+	t.Run("should read the limit from properties", func(t *testing.T) {
+		g := New(Properties(map[string]string{"goat.lifecycle.concurrency": "8"}))
+		assert.Equal(t, 8, g.lifecycle.concurrency)
+		assert.Equal(t, 1, New().lifecycle.concurrency)
+	})
+
+	t.Run("should fail start if the limit is less than 1", func(t *testing.T) {
+		g := New(Properties(map[string]string{"goat.lifecycle.concurrency": "0"}))
+		assert.ErrorContains(t, g.Start(context.Background()), "invalid property goat.lifecycle.concurrency")
+		assert.ErrorContains(t, New(HookConcurrency(-1)).Start(context.Background()), "goat.HookConcurrency must be at least 1")
+	})
+}
+
+type testPhasedService struct {
+	testHookService
+	phase int
+}
+
+func (s *testPhasedService) Phase() int {
+	return s.phase
+}
+
+func Test_Goat_Phase(t *testing.T) {
+	t.Run("should start phases in ascending order and stop them in descending order", func(t *testing.T) {
+		r := &testRecorder{}
+		g := New(
+			Provide(func() *testPhasedService {
+				return &testPhasedService{testHookService: testHookService{name: "listener", recorder: r}, phase: 10}
+			}),
+			Invoke(func(s *testPhasedService, lc Lifecycle) {
+				lc.Append(Hook{OnStart: r.record("invoke start"), OnStop: r.record("invoke stop")})
+				lc.Append(Hook{OnStart: r.record("early start"), OnStop: r.record("early stop"), Phase: -1})
+			}),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, []string{"early start", "invoke start", "listener start"}, r.events)
+		assert.NoError(t, g.Stop(context.Background()))
+		assert.Equal(t, []string{
+			"early start", "invoke start", "listener start",
+			"listener stop", "invoke stop", "early stop",
+		}, r.events)
+	})
+
+	t.Run("should keep dependency order within a phase", func(t *testing.T) {
+		r := &testRecorder{}
+		g := New(
+			HookConcurrency(4),
+			Provide(func() *testHookService { return &testHookService{name: "service", recorder: r} }),
+			Invoke(func(s *testHookService, lc Lifecycle) {
+				lc.Append(Hook{OnStart: r.record("invoke start")})
+			}),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, []string{"service start", "invoke start"}, r.events)
+	})
+
+	t.Run("should start phases in order even if a hook depends on a hook of a later phase", func(t *testing.T) {
+		r := &testRecorder{}
+		type listener struct{ *testPhasedService }
+		g := New(
+			Provide(func() *testPhasedService {
+				return &testPhasedService{testHookService: testHookService{name: "database", recorder: r}, phase: 10}
+			}),
+			Provide(func(db *testPhasedService) *listener {
+				return &listener{&testPhasedService{testHookService: testHookService{name: "listener", recorder: r}}}
+			}),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.NoError(t, g.Stop(context.Background()))
+		assert.Equal(t, []string{"listener start", "database start", "database stop", "listener stop"}, r.events)
+	})
+
+	t.Run("should start a hook after its dependency of an earlier phase", func(t *testing.T) {
+		r := &testRecorder{}
+		type listener struct{ *testPhasedService }
+		g := New(
+			Provide(func() *testPhasedService {
+				return &testPhasedService{testHookService: testHookService{name: "database", recorder: r}, phase: -1}
+			}),
+			Provide(func(db *testPhasedService) *listener {
+				return &listener{&testPhasedService{testHookService: testHookService{name: "listener", recorder: r}, phase: 5}}
+			}),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.NoError(t, g.Stop(context.Background()))
+		assert.Equal(t, []string{"database start", "listener start", "listener stop", "database stop"}, r.events)
+	})
+
+	t.Run("should not start later phases if a phase fails", func(t *testing.T) {
+		r := &testRecorder{}
+		g := New(Invoke(func(lc Lifecycle) {
+			lc.Append(Hook{OnStart: r.record("a start"), OnStop: r.record("a stop")})
+			lc.Append(Hook{OnStart: func(ctx context.Context) error { return errors.New("b failed") }, Phase: 1})
+			lc.Append(Hook{OnStart: r.record("c start"), Phase: 2})
+		}))
+		assert.ErrorContains(t, g.Start(context.Background()), "b failed")
+		assert.Equal(t, []string{"a start", "a stop"}, r.events)
+	})
+}
@@ -0,0 +1,27 @@
+package goat
+
+type module struct {
+	goat  *Goat
+	scope *scope
+}
+
+// Module bundles options under a name. Components provided with Private inside it
+// are only visible to the module and its nested modules, and errors are prefixed with the module path.
+func Module(name string, opts ...Option) Option {
+	return optionFunc(func(m *module) {
+		child := &module{
+			goat:  m.goat,
+			scope: newScope(name, m.scope),
+		}
+		for _, opt := range opts {
+			if opt == nil {
+				continue
+			}
+			opt.apply(child)
+		}
+	})
+}
+
+func (m *module) fail(err error) {
+	m.goat.fail(err)
+}
@@ -0,0 +1,118 @@
+package goat
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_Goat_Module(t *testing.T) {
+	t.Run("should expose public providers of a module to the whole app", func(t *testing.T) {
+		var bar *testBar
+		g := New(
+			Module("storage",
+				Provide(newTestFoo),
+			),
+			Provide(newTestBar),
+			Invoke(func(b *testBar) { bar = b }),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, "foo", bar.foo.name)
+	})
+
+	t.Run("should hide private providers outside the module", func(t *testing.T) {
+		g := New(
+			Module("storage",
+				Provide(newTestFoo, Private),
+			),
+			Provide(newTestBar),
+		)
+		err := g.Start(context.Background())
+		assert.ErrorContains(t, err, "app > github.com/PCloud63514/goat.newTestBar")
+		assert.ErrorContains(t, err, "missing dependency *goat.testFoo")
+	})
+
+	t.Run("should let the module and nested modules use private providers", func(t *testing.T) {
+		var baz *testBaz
+		g := New(
+			Module("storage",
+				Provide(newTestFoo, Private),
+				Module("cache",
+					Provide(newTestBar),
+				),
+			),
+			Provide(newTestBaz),
+			Invoke(func(b *testBaz) { baz = b }),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, "foo", baz.bar.foo.name)
+	})
+
+	t.Run("should allow the same private type in sibling modules", func(t *testing.T) {
+		names := make([]string, 0)
+		g := New(
+			Module("a",
+				Provide(func() *testFoo { return &testFoo{name: "a"} }, Private),
+				Invoke(func(f *testFoo) { names = append(names, f.name) }),
+			),
+			Module("b",
+				Provide(func() *testFoo { return &testFoo{name: "b"} }, Private),
+				Invoke(func(f *testFoo) { names = append(names, f.name) }),
+			),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, []string{"a", "b"}, names)
+	})
+
+	t.Run("should prefix errors with the module path", func(t *testing.T) {
+		g := New(
+			Module("app-storage",
+				Module("db",
+					Provide(func() (*testDB, error) { return nil, errors.New("connection refused") }),
+				),
+			),
+		)
+		err := g.Start(context.Background())
+		assert.ErrorContains(t, err, "app > app-storage > db > github.com/PCloud63514/goat.Test_Goat_Module")
+		assert.ErrorContains(t, err, "failed: connection refused")
+	})
+
+	t.Run("should apply module decorators only inside the module", func(t *testing.T) {
+		var inside, outside *testFoo
+		g := New(
+			Provide(newTestFoo),
+			Module("logging",
+				Decorate(func(f *testFoo) *testFoo { return &testFoo{name: f.name + "-logged"} }),
+				Invoke(func(f *testFoo) { inside = f }),
+			),
+			Invoke(func(f *testFoo) { outside = f }),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, "foo-logged", inside.name)
+		assert.Equal(t, "foo", outside.name)
+	})
+
+	t.Run("should not decorate what the module cannot see", func(t *testing.T) {
+		g := New(
+			Module("a", Provide(newTestFoo, Private)),
+			Module("b", Decorate(func(f *testFoo) *testFoo { return f })),
+		)
+		assert.ErrorContains(t, g.Start(context.Background()), "app > b > decorator")
+	})
+
+	t.Run("should bundle configurations in a module", func(t *testing.T) {
+		type serverConfiguration struct {
+			Host string `properties:"server.host"`
+		}
+		var cfg serverConfiguration
+		g := New(
+			Module("server",
+				Configuration(serverConfiguration{}),
+				Invoke(func(c serverConfiguration) { cfg = c }),
+			),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, "localhost", cfg.Host)
+	})
+}
@@ -0,0 +1,289 @@
+package goat
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// In is embedded in a parameter struct to have each exported field injected.
+// Fields accept the `name:"..."`, `optional:"true"` and `group:"..."` tags.
+type In struct{}
+
+// Out is embedded in a result struct to provide each exported field as a separate component.
+// Fields accept the `name:"..."` and `group:"..."` tags.
+type Out struct{}
+
+// Optional is a parameter type for a dependency that may not be provided.
+// Start passes it empty instead of failing when nothing provides T.
+type Optional[T any] struct {
+	value T
+	ok    bool
+}
+
+// Get returns the dependency and whether it was provided.
+func (o Optional[T]) Get() (T, bool) {
+	return o.value, o.ok
+}
+
+// OrElse returns the dependency, or value if it was not provided.
+func (o Optional[T]) OrElse(value T) T {
+	if o.ok {
+		return o.value
+	}
+	return value
+}
+
+func (o Optional[T]) optionalType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func (o *Optional[T]) setOptional(v reflect.Value) {
+	reflect.ValueOf(&o.value).Elem().Set(v)
+	o.ok = true
+}
+
+type optional interface {
+	optionalType() reflect.Type
+	setOptional(v reflect.Value)
+}
+
+var (
+	inType       = reflect.TypeOf(In{})
+	outType      = reflect.TypeOf(Out{})
+	optionalType = reflect.TypeOf((*optional)(nil)).Elem()
+)
+
+type param interface {
+	fmt.Stringer
+	deps() []key
+	build(c *container, s *scope) (reflect.Value, error)
+}
+
+type paramSingle struct {
+	key      key
+	optional bool
+}
+
+type paramLifecycle struct{}
+
+type paramEvents struct{}
+
+type paramOptional struct {
+	t   reflect.Type
+	key key
+}
+
+type paramObject struct {
+	t      reflect.Type
+	fields []paramField
+}
+
+type paramField struct {
+	name  string
+	index int
+	param param
+}
+
+func newParams(fnType reflect.Type, tags []reflect.StructTag) ([]param, error) {
+	if len(tags) > fnType.NumIn() {
+		return nil, fmt.Errorf("%d parameters but %d param tags were given", fnType.NumIn(), len(tags))
+	}
+	params := make([]param, fnType.NumIn())
+	for i := range params {
+		tag := reflect.StructTag("")
+		if i < len(tags) {
+			tag = tags[i]
+		}
+		p, err := newParam(fnType.In(i), tag)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %d: %w", i, err)
+		}
+		params[i] = p
+	}
+	return params, nil
+}
+
+func newParam(t reflect.Type, tag reflect.StructTag) (param, error) {
+	if embeds(t, inType) {
+		return newParamObject(t)
+	}
+	if t == lifecycleType {
+		return paramLifecycle{}, nil
+	}
+	if t == eventsType {
+		return paramEvents{}, nil
+	}
+	if reflect.PointerTo(t).Implements(optionalType) {
+		elem := reflect.New(t).Interface().(optional).optionalType()
+		return paramOptional{t: t, key: key{t: elem, name: tag.Get("name")}}, nil
+	}
+	p := paramSingle{
+		key:      key{t: t, name: tag.Get("name")},
+		optional: tag.Get("optional") == "true",
+	}
+	if group := tag.Get("group"); group != "" {
+		if t.Kind() != reflect.Slice || p.key.name != "" {
+			return nil, fmt.Errorf("group %q must be an unnamed slice, got %v", group, t)
+		}
+		p.key = key{t: t.Elem(), group: group}
+	}
+	return p, nil
+}
+
+func newParamObject(t reflect.Type) (param, error) {
+	p := paramObject{t: t, fields: make([]paramField, 0, t.NumField())}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type == inType {
+			continue
+		}
+		if !f.IsExported() {
+			return nil, fmt.Errorf("field %s of %v must be exported", f.Name, t)
+		}
+		fp, err := newParam(f.Type, f.Tag)
+		if err != nil {
+			return nil, fmt.Errorf("field %s of %v: %w", f.Name, t, err)
+		}
+		p.fields = append(p.fields, paramField{name: f.Name, index: i, param: fp})
+	}
+	return p, nil
+}
+
+func (p paramSingle) String() string {
+	return p.key.String()
+}
+
+func (p paramSingle) deps() []key {
+	return []key{p.key}
+}
+
+func (p paramSingle) build(c *container, s *scope) (reflect.Value, error) {
+	if p.optional && p.key.group == "" && len(c.providersOf(s, p.key)) == 0 {
+		return reflect.Zero(p.key.t), nil
+	}
+	return c.resolve(s, p.key)
+}
+
+func (p paramLifecycle) String() string {
+	return lifecycleType.String()
+}
+
+func (p paramLifecycle) deps() []key {
+	return nil
+}
+
+func (p paramLifecycle) build(c *container, s *scope) (reflect.Value, error) {
+	var l Lifecycle = &ownedLifecycle{container: c, owner: c.owner}
+	return reflect.ValueOf(&l).Elem(), nil
+}
+
+func (p paramEvents) String() string {
+	return eventsType.String()
+}
+
+func (p paramEvents) deps() []key {
+	return nil
+}
+
+func (p paramEvents) build(c *container, s *scope) (reflect.Value, error) {
+	var e Events = c.events
+	return reflect.ValueOf(&e).Elem(), nil
+}
+
+func (p paramOptional) String() string {
+	return fmt.Sprintf("%v(%v)", p.t.Name(), p.key)
+}
+
+func (p paramOptional) deps() []key {
+	return []key{p.key}
+}
+
+func (p paramOptional) build(c *container, s *scope) (reflect.Value, error) {
+	v := reflect.New(p.t)
+	if len(c.providersOf(s, p.key)) == 0 {
+		return v.Elem(), nil
+	}
+	dep, err := c.resolve(s, p.key)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	v.Interface().(optional).setOptional(dep)
+	return v.Elem(), nil
+}
+
+func (p paramObject) String() string {
+	return p.t.String()
+}
+
+func (p paramObject) deps() []key {
+	keys := make([]key, 0, len(p.fields))
+	for _, f := range p.fields {
+		keys = append(keys, f.param.deps()...)
+	}
+	return keys
+}
+
+func (p paramObject) build(c *container, s *scope) (reflect.Value, error) {
+	v := reflect.New(p.t).Elem()
+	for _, f := range p.fields {
+		fv, err := f.param.build(c, s)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("field %s (%v): %w", f.name, f.param, err)
+		}
+		v.Field(f.index).Set(fv)
+	}
+	return v, nil
+}
+
+func newResults(t reflect.Type, index int, opts provideOptions) ([]result, error) {
+	if !embeds(t, outType) {
+		results := []result{{index: index, field: -1, key: key{t: t, name: opts.name, group: opts.group}}}
+		for _, iface := range opts.as {
+			if !t.Implements(iface) {
+				return nil, fmt.Errorf("%v does not implement %v", t, iface)
+			}
+			results = append(results, result{index: index, field: -1, key: key{t: iface, name: opts.name, group: opts.group}})
+		}
+		return results, nil
+	}
+	if opts.name != "" || opts.group != "" || len(opts.as) > 0 {
+		return nil, fmt.Errorf("goat.Name, goat.Group and goat.As do not apply to the goat.Out result %v, use the name and group tags on its fields instead", t)
+	}
+
+	results := make([]result, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type == outType {
+			continue
+		}
+		if !f.IsExported() {
+			return nil, fmt.Errorf("field %s of %v must be exported", f.Name, t)
+		}
+		k := key{t: f.Type, name: f.Tag.Get("name"), group: f.Tag.Get("group")}
+		if k.name != "" && k.group != "" {
+			return nil, fmt.Errorf("field %s of %v cannot have both name and group", f.Name, t)
+		}
+		results = append(results, result{index: index, field: i, key: k})
+	}
+	return results, nil
+}
+
+func embeds(t reflect.Type, marker reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.Anonymous && f.Type == marker {
+			return true
+		}
+	}
+	return false
+}
+
+func paramDeps(params []param) []key {
+	keys := make([]key, 0, len(params))
+	for _, p := range params {
+		keys = append(keys, p.deps()...)
+	}
+	return keys
+}
@@ -0,0 +1,191 @@
+package goat
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type testParams struct {
+	In
+
+	Foo     *testFoo
+	Primary *testDB        `name:"primary"`
+	Replica *testDB        `name:"replica" optional:"true"`
+	Routes  []testRoute    `group:"routes"`
+	Service testFooService `optional:"true"`
+}
+
+type testResults struct {
+	Out
+
+	Primary *testDB   `name:"primary"`
+	Route   testRoute `group:"routes"`
+	Bar     *testBar
+}
+
+func Test_Goat_In(t *testing.T) {
+	t.Run("should inject every field of the parameter struct", func(t *testing.T) {
+		var params testParams
+		g := New(
+			Provide(newTestFoo),
+			Provide(func() *testDB { return &testDB{dsn: "primary"} }, Name("primary")),
+			Provide(func() testRoute { return "/a" }, Group("routes")),
+			Provide(func(p testParams) *testBaz {
+				params = p
+				return &testBaz{}
+			}),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, "foo", params.Foo.name)
+		assert.Equal(t, "primary", params.Primary.dsn)
+		assert.Nil(t, params.Replica)
+		assert.Nil(t, params.Service)
+		assert.Equal(t, []testRoute{"/a"}, params.Routes)
+	})
+
+	t.Run("should name the field that could not be satisfied", func(t *testing.T) {
+		g := New(
+			Provide(newTestFoo),
+			Provide(func(p testParams) *testBaz { return &testBaz{} }),
+		)
+		err := g.Start(context.Background())
+		assert.ErrorContains(t, err, `field Primary (*goat.testDB[name="primary"]): missing dependency`)
+	})
+
+	t.Run("should reject unexported fields", func(t *testing.T) {
+		type params struct {
+			In
+			foo *testFoo
+		}
+		g := New(Provide(func(p params) *testBaz { return &testBaz{} }))
+		assert.ErrorContains(t, g.Start(context.Background()), "field foo")
+	})
+
+	t.Run("should detect cycle through parameter struct", func(t *testing.T) {
+		type params struct {
+			In
+			Bar *testBar
+		}
+		g := New(
+			Provide(func(p params) *testFoo { return &testFoo{} }),
+			Provide(newTestBar),
+		)
+		assert.ErrorContains(t, g.Start(context.Background()), "*goat.testFoo -> *goat.testBar -> *goat.testFoo")
+	})
+}
+
+func Test_Goat_Out(t *testing.T) {
+	t.Run("should provide every field of the result struct", func(t *testing.T) {
+		var params testParams
+		var bar *testBar
+		g := New(
+			Provide(newTestFoo),
+			Provide(func(foo *testFoo) testResults {
+				return testResults{
+					Primary: &testDB{dsn: "primary"},
+					Route:   "/out",
+					Bar:     newTestBar(foo),
+				}
+			}),
+			Provide(func(p testParams, b *testBar) *testBaz {
+				params, bar = p, b
+				return &testBaz{}
+			}),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Equal(t, "primary", params.Primary.dsn)
+		assert.Equal(t, []testRoute{"/out"}, params.Routes)
+		assert.Equal(t, "foo", bar.foo.name)
+	})
+
+	t.Run("should reject field with both name and group", func(t *testing.T) {
+		type results struct {
+			Out
+			DB *testDB `name:"a" group:"b"`
+		}
+		g := New(Provide(func() results { return results{} }))
+		assert.ErrorContains(t, g.Start(context.Background()), "cannot have both name and group")
+	})
+
+	t.Run("should reject name, group and as annotations on a result struct", func(t *testing.T) {
+		for _, opt := range []ProvideOption{Name("primary"), Group("routes"), As(new(testFooService))} {
+			g := New(Provide(func() testResults { return testResults{} }, opt))
+			err := g.Start(context.Background())
+			assert.ErrorContains(t, err, "do not apply to the goat.Out result goat.testResults")
+			assert.ErrorContains(t, err, "use the name and group tags on its fields instead")
+		}
+	})
+}
+
+type testTracer struct{}
+
+func Test_Goat_Optional(t *testing.T) {
+	t.Run("should pass empty optional if nothing provides the dependency", func(t *testing.T) {
+		var tracer Optional[*testTracer]
+		g := New(Provide(func(o Optional[*testTracer]) *testFoo {
+			tracer = o
+			return newTestFoo()
+		}))
+		assert.NoError(t, g.Start(context.Background()))
+		v, ok := tracer.Get()
+		assert.False(t, ok)
+		assert.Nil(t, v)
+		fallback := &testTracer{}
+		assert.Same(t, fallback, tracer.OrElse(fallback))
+	})
+
+	t.Run("should pass provided dependency with presence", func(t *testing.T) {
+		provided := &testTracer{}
+		var tracer Optional[*testTracer]
+		g := New(
+			Provide(func() *testTracer { return provided }),
+			Provide(func(o Optional[*testTracer]) *testFoo {
+				tracer = o
+				return newTestFoo()
+			}),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		v, ok := tracer.Get()
+		assert.True(t, ok)
+		assert.Same(t, provided, v)
+	})
+
+	t.Run("should support optional interface dependency as parameter struct field", func(t *testing.T) {
+		type params struct {
+			In
+			Service Optional[testFooService] `name:"service"`
+		}
+		var service Optional[testFooService]
+		g := New(
+			Provide(func() testFooService { return newTestFoo() }, Name("service")),
+			Provide(func(p params) *testBar {
+				service = p.Service
+				return &testBar{}
+			}),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+		v, ok := service.Get()
+		assert.True(t, ok)
+		assert.Equal(t, "foo", v.Name())
+	})
+
+	t.Run("should pass zero value for optional param tag", func(t *testing.T) {
+		called := false
+		g := New(Provide(func(tracer *testTracer) *testFoo {
+			called = true
+			assert.Nil(t, tracer)
+			return newTestFoo()
+		}, ParamTags(`optional:"true"`)))
+		assert.NoError(t, g.Start(context.Background()))
+		assert.True(t, called)
+	})
+
+	t.Run("should fail start if optional dependency fails to build", func(t *testing.T) {
+		g := New(
+			Provide(func(foo *testFoo) *testTracer { return &testTracer{} }),
+			Provide(func(o Optional[*testTracer]) *testBar { return &testBar{} }),
+		)
+		assert.ErrorContains(t, g.Start(context.Background()), "missing dependency *goat.testFoo")
+	})
+}
@@ -24,6 +24,12 @@ func New() *Profile {
 	}
 }
 
+func Of(profiles ...string) *Profile {
+	return &Profile{
+		value: mergeSlicesUnique([]string{ProfileDefault}, profiles),
+	}
+}
+
 func (p *Profile) Get() []string {
 	return p.value
 }
@@ -0,0 +1,139 @@
+package goat
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	startTimeoutProperty = "goat.lifecycle.start-timeout"
+	stopTimeoutProperty  = "goat.lifecycle.stop-timeout"
+	dumpFileProperty     = "goat.lifecycle.dump-file"
+	concurrencyProperty  = "goat.lifecycle.concurrency"
+)
+
+type settings struct {
+	profiles     []string
+	properties   map[string]string
+	resourcePath string
+	logger       *log.Logger
+	startTimeout time.Duration
+	stopTimeout  time.Duration
+	dumpFile     string
+	concurrency  int
+}
+
+type settingOption func(s *settings)
+
+// settingOption is applied by New before the profiles and the Environment are created,
+// so it fails when passed anywhere but to New directly, such as in Module or OnProfile.
+func (f settingOption) apply(m *module) {
+	m.fail(fmt.Errorf("%s > app settings such as goat.Profiles must be passed to goat.New directly", m.scope.path()))
+}
+
+// Profiles activates the given profiles instead of the ones read from os.Args.
+func Profiles(profiles ...string) Option {
+	return settingOption(func(s *settings) {
+		s.profiles = append(make([]string, 0, len(profiles)), profiles...)
+	})
+}
+
+// Properties sets properties on the Environment, taking precedence over the profile resources.
+func Properties(properties map[string]string) Option {
+	return settingOption(func(s *settings) {
+		if s.properties == nil {
+			s.properties = make(map[string]string)
+		}
+		for k, v := range properties {
+			s.properties[k] = v
+		}
+	})
+}
+
+// ResourcePath sets the directory the profile resources are loaded from instead of res.
+func ResourcePath(path string) Option {
+	return settingOption(func(s *settings) {
+		s.resourcePath = path
+	})
+}
+
+// Logger sets the logger goat reports startup decisions to. The default writes to stderr.
+func Logger(logger *log.Logger) Option {
+	return settingOption(func(s *settings) {
+		s.logger = logger
+	})
+}
+
+// StartTimeout bounds the OnStart hooks run by Goat.Start. Building the components is not bounded.
+// Without it the goat.lifecycle.start-timeout property is used, e.g. goat.lifecycle.start-timeout=15s.
+func StartTimeout(timeout time.Duration) Option {
+	return settingOption(func(s *settings) {
+		s.startTimeout = timeout
+	})
+}
+
+// StopTimeout bounds Goat.Stop, including every OnStop and OnShutdown hook. Goat.Run exits with
+// ExitCodeShutdownTimeout once it passed, even if a cleanup function is still running.
+// Without it the goat.lifecycle.stop-timeout property is used, e.g. goat.lifecycle.stop-timeout=30s.
+func StopTimeout(timeout time.Duration) Option {
+	return settingOption(func(s *settings) {
+		s.stopTimeout = timeout
+	})
+}
+
+// GoroutineDumpFile sets the file the goroutine dump is written to when shutdown hangs instead of stderr.
+// Without it the goat.lifecycle.dump-file property is used.
+func GoroutineDumpFile(path string) Option {
+	return settingOption(func(s *settings) {
+		s.dumpFile = path
+	})
+}
+
+// HookConcurrency sets how many lifecycle hooks may run at once, n being at least 1. Hooks still wait for the hooks
+// of the components they depend on, and on stop for the hooks of the components depending on them.
+// Without it the goat.lifecycle.concurrency property is used. The default is 1: hooks run one at a time
+// in registration order and nothing runs in parallel unless a higher limit is set.
+func HookConcurrency(n int) Option {
+	return settingOption(func(s *settings) {
+		s.concurrency = n
+	})
+}
+
+func (g *Goat) concurrencySetting() int {
+	if g.settings.concurrency != 0 {
+		if g.settings.concurrency < 1 {
+			g.fail(&exitError{err: fmt.Errorf("goat.HookConcurrency must be at least 1, got %d", g.settings.concurrency), code: ExitCodeConfigInvalid})
+			return 1
+		}
+		return g.settings.concurrency
+	}
+	if !g.environment.ContainsProperty(concurrencyProperty) {
+		return 1
+	}
+	n, err := g.environment.GetRequiredPropertyInt(concurrencyProperty)
+	if err == nil && n < 1 {
+		err = fmt.Errorf("%d is less than 1", n)
+	}
+	if err != nil {
+		g.fail(&exitError{err: fmt.Errorf("invalid property %s: %w", concurrencyProperty, err), code: ExitCodeConfigInvalid})
+		return 1
+	}
+	return n
+}
+
+func (g *Goat) timeoutSetting(timeout time.Duration, key string) time.Duration {
+	if timeout > 0 {
+		return timeout
+	}
+	value := g.environment.GetProperty(key, "")
+	if value == "" {
+		return 0
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		g.fail(&exitError{err: fmt.Errorf("invalid property %s: %w", key, err), code: ExitCodeConfigInvalid})
+		return 0
+	}
+	return timeout
+}
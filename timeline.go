@@ -0,0 +1,69 @@
+package goat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Timeline reports how long each constructor call and each OnStart hook took on Goat.Start.
+// It is encoded as JSON as is, durations being in nanoseconds.
+type Timeline struct {
+	StartUpDateTime time.Time      `json:"startUpDateTime"`
+	Duration        time.Duration  `json:"durationNs"`
+	Steps           []TimelineStep `json:"steps"`
+}
+
+// TimelineStep is a constructor call or an OnStart hook. Steps run concurrently may overlap.
+type TimelineStep struct {
+	Kind     string        `json:"kind"`
+	Name     string        `json:"name"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"durationNs"`
+}
+
+// String formats the steps as a table, longest first.
+func (t Timeline) String() string {
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "startup took %v\n", t.Duration)
+	w := tabwriter.NewWriter(sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DURATION\tKIND\tNAME")
+	for _, step := range t.Steps {
+		fmt.Fprintf(w, "%v\t%s\t%s\n", step.Duration, step.Kind, step.Name)
+	}
+	w.Flush()
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+type timeline struct {
+	mu    sync.Mutex
+	steps []TimelineStep
+}
+
+func newTimeline() *timeline {
+	return &timeline{steps: make([]TimelineStep, 0)}
+}
+
+func (t *timeline) record(kind string, name string, start time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.steps = append(t.steps, TimelineStep{Kind: kind, Name: name, Start: start, Duration: time.Since(start)})
+}
+
+// Timeline returns the steps recorded so far, sorted by duration, longest first.
+func (g *Goat) Timeline() Timeline {
+	g.timeline.mu.Lock()
+	steps := append([]TimelineStep(nil), g.timeline.steps...)
+	g.timeline.mu.Unlock()
+	sort.SliceStable(steps, func(i, j int) bool {
+		return steps[i].Duration > steps[j].Duration
+	})
+	return Timeline{
+		StartUpDateTime: g.startUpDateTime,
+		Duration:        g.startUpDuration,
+		Steps:           steps,
+	}
+}
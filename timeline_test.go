@@ -0,0 +1,65 @@
+package goat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"testing"
+	"time"
+)
+
+func Test_Goat_Timeline(t *testing.T) {
+	t.Run("should record constructors and start hooks sorted by duration", func(t *testing.T) {
+		g := New(
+			Provide(func() *testFoo {
+				time.Sleep(10 * time.Millisecond)
+				return newTestFoo()
+			}),
+			Provide(newTestBar),
+			Invoke(func(lc Lifecycle) {
+				lc.Append(Hook{OnStart: func(ctx context.Context) error {
+					time.Sleep(30 * time.Millisecond)
+					return nil
+				}})
+				lc.Append(Hook{OnStop: func(ctx context.Context) error { return nil }})
+			}),
+		)
+		assert.NoError(t, g.Start(context.Background()))
+
+		timeline := g.Timeline()
+		assert.Equal(t, g.startUpDateTime, timeline.StartUpDateTime)
+		assert.Equal(t, g.startUpDuration, timeline.Duration)
+		assert.Len(t, timeline.Steps, 3)
+		assert.Equal(t, "OnStart", timeline.Steps[0].Kind)
+		assert.Contains(t, timeline.Steps[0].Name, "app > github.com/PCloud63514/goat.Test_Goat_Timeline")
+		assert.GreaterOrEqual(t, timeline.Steps[0].Duration, 30*time.Millisecond)
+		assert.Equal(t, "constructor", timeline.Steps[1].Kind)
+		assert.GreaterOrEqual(t, timeline.Steps[1].Duration, 10*time.Millisecond)
+		assert.Equal(t, "app > github.com/PCloud63514/goat.newTestBar", timeline.Steps[2].Name)
+	})
+
+	t.Run("should encode the timeline as json", func(t *testing.T) {
+		g := New(Provide(newTestFoo))
+		assert.NoError(t, g.Start(context.Background()))
+
+		data, err := json.Marshal(g.Timeline())
+		assert.NoError(t, err)
+		var decoded map[string]interface{}
+		assert.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Contains(t, decoded, "startUpDateTime")
+		assert.Contains(t, decoded, "durationNs")
+		step := decoded["steps"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "constructor", step["kind"])
+		assert.Equal(t, "app > github.com/PCloud63514/goat.newTestFoo", step["name"])
+	})
+
+	t.Run("should log the timeline as a table at the end of startup", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		g := New(Logger(log.New(buf, "", 0)), Provide(newTestFoo))
+		assert.NoError(t, g.Start(context.Background()))
+		assert.Contains(t, buf.String(), "startup took ")
+		assert.Regexp(t, `DURATION +KIND +NAME\n\S+ +constructor +app > github.com/PCloud63514/goat.newTestFoo`, buf.String())
+	})
+}